@@ -1,16 +1,22 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/derivative"
 	"github.com/Erlendum/BMSTU_CC/lab_01/internal/dfa"
-	infixToPostix "github.com/Erlendum/BMSTU_CC/lab_01/internal/infixToPostfix"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
 	"github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/pikevm"
 )
 
 const (
+	astFileName    = "ast.dot"
 	nfaFileName    = "nfa.dot"
 	dfaFileName    = "dfa.dot"
 	minDFAFileName = "min_dfa.dot"
@@ -31,12 +37,109 @@ func prepareStepsDir(dir string) error {
 	return nil
 }
 
+// writeSteps сохраняет кадры моделирования в stepsDir как step_1.dot, step_2.dot и т.д.
+func writeSteps(steps []string) error {
+	err := prepareStepsDir(stepsDir)
+	if err != nil {
+		return fmt.Errorf("ошибка подготовки папки: %w", err)
+	}
+
+	for i, step := range steps {
+		filename := fmt.Sprintf(stepsDir+"/step_%d.dot", i+1)
+		err := os.WriteFile(filename, []byte(step), 0644)
+		if err != nil {
+			return fmt.Errorf("ошибка при записи файла %s: %w", filename, err)
+		}
+		fmt.Printf("Step %d сохранен как %s\n", i+1, filename)
+	}
+
+	return nil
+}
+
+// minimize минимизирует ДКА выбранным алгоритмом: brzozowski (двойное
+// обращение) или hopcroft (разбиение на классы эквивалентности).
+func minimize(d *dfa.DFA, strategy string) (*dfa.DFA, error) {
+	switch strategy {
+	case "brzozowski":
+		return d.Minimize(), nil
+	case "hopcroft":
+		return d.MinimizeHopcroft(), nil
+	}
+	return nil, fmt.Errorf("неизвестный алгоритм минимизации %q (доступны brzozowski, hopcroft)", strategy)
+}
+
+// buildNFA строит НКА выбранным способом: thompson (по Томпсону), epsfree (по
+// Томпсону с удалением эпсилон-переходов) или glushkov (позиционный НКА).
+func buildNFA(tree *ast.Node, construction string) (*nfa.NFA, error) {
+	switch construction {
+	case "thompson":
+		return dfa.CompileNFA(tree), nil
+	case "epsfree":
+		return dfa.CompileNFA(tree).RemoveEpsilon(), nil
+	case "glushkov":
+		return dfa.Glushkov(tree)
+	}
+	return nil, fmt.Errorf("неизвестный способ построения НКА %q (доступны thompson, epsfree, glushkov)", construction)
+}
+
+// captureMode выбирает правило выбора подгрупп Pike VM.
+func captureMode(semantics string) (pikevm.Mode, error) {
+	switch semantics {
+	case "leftmost-first":
+		return pikevm.LeftmostFirst, nil
+	case "posix":
+		return pikevm.LeftmostLongest, nil
+	}
+	return 0, fmt.Errorf("неизвестное правило %q (доступны leftmost-first, posix)", semantics)
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "да"
+	}
+	return "нет"
+}
+
+// printRegexError печатает ошибку разбора и ставит каретку под символом, на
+// котором она обнаружена.
+func printRegexError(regex string, err error) {
+	fmt.Println("ошибка в регулярном выражении:", err)
+
+	var syntaxErr *lexer.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		fmt.Println(regex)
+		fmt.Println(strings.Repeat(" ", syntaxErr.Offset) + "^")
+	}
+}
+
 func main() {
-	mode := flag.String("mode", "nfa", "Режим работы (nfa, dfa, minDFA, modeling), по умолчанию будет nfa (построение НКА)")
+	mode := flag.String("mode", "nfa", "Режим работы (ast, nfa, dfa, minDFA, modeling, nfaModeling, search, equiv, analyze, toRegex, followpos, derivativeDFA, lazy, captures, subsetTrace, minDFASteps), по умолчанию будет nfa (построение НКА)")
 	regex := flag.String("regex", "(ab)*c", "Регулярное выражение, по умолчанию будет (ab)*c")
-	input := flag.String("input", "abc", "Входная строка для режима modeling, по умолчанию будет abc")
+	regex2 := flag.String("regex2", "", "Второе регулярное выражение для режима equiv")
+	input := flag.String("input", "abc", "Входная строка для режимов modeling, nfaModeling, search, lazy и captures, по умолчанию будет abc")
+	flags := flag.String("flags", "", "Флаги для всего выражения, как в (?flags): i - без учета регистра")
+	strategy := flag.String("minimize", "brzozowski", "Алгоритм минимизации (brzozowski, hopcroft), по умолчанию будет brzozowski")
+	length := flag.Int("n", 3, "Длина строк для подсчета в режиме analyze, по умолчанию будет 3")
+	limit := flag.Int("k", 10, "Сколько первых строк языка вывести в режиме analyze, по умолчанию будет 10")
+	construction := flag.String("nfa", "thompson", "Способ построения НКА в режимах nfa, dfa, nfaModeling и subsetTrace (thompson, epsfree, glushkov), по умолчанию будет thompson")
+	semantics := flag.String("semantics", "leftmost-first", "Правило выбора подгрупп в режиме captures (leftmost-first, posix), по умолчанию будет leftmost-first")
+	cacheSize := flag.Int("cache", 4096, "Наибольшее число состояний в кеше ленивого ДКА в режиме lazy, по умолчанию будет 4096")
+	format := flag.String("format", "markdown", "Формат таблиц в режиме subsetTrace (markdown, latex), по умолчанию будет markdown")
+	complete := flag.Bool("complete", false, "Дополнить ДКА тупиковым состоянием в режимах dfa, minDFA и modeling")
 	flag.Parse()
 
+	regexFlags, err := lexer.ParseFlags(*flags)
+	if err != nil {
+		fmt.Println("ошибка в флагах:", err)
+		return
+	}
+
+	tree, err := ast.ParseWithFlags(*regex, regexFlags)
+	if err != nil {
+		printRegexError(*regex, err)
+		return
+	}
+
 	switch *mode {
 	// case "test":
 	// 	a := dfa.NewState(0, nil, false)
@@ -61,54 +164,101 @@ func main() {
 	// 	}
 	// 	fmt.Printf("DFA сохранен в файл: %s\n", dfaFileName)
 	// 	dfa.Minimize()
+	case "ast":
+		err := os.WriteFile(astFileName, []byte(tree.ToDot()), 0644)
+		if err != nil {
+			fmt.Println("ошибка при записи файла:", err)
+			return
+		}
+		fmt.Printf("AST сохранено в файл: %s\n", astFileName)
+		fmt.Printf("Каноническая запись: %s\n", tree)
+		fmt.Printf("Постфиксная запись: %s\n", tree.Postfix())
 	case "nfa":
-		postfix := infixToPostix.Transform(*regex)
-
-		graphvizNFA := nfa.Build(postfix).ToGraphviz()
+		builtNFA, err := buildNFA(tree, *construction)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 
-		err := os.WriteFile(nfaFileName, []byte(graphvizNFA), 0644)
+		err = os.WriteFile(nfaFileName, []byte(builtNFA.ToGraphviz()), 0644)
 		if err != nil {
 			fmt.Println("ошибка при записи файла:", err)
 			return
 		}
 		fmt.Printf("NFA сохранен в файл: %s\n", nfaFileName)
+		fmt.Printf("Состояний: %d\n", len(builtNFA.States()))
 	case "dfa":
-		postfix := infixToPostix.Transform(*regex)
-		graphvizDFA := dfa.Build(nfa.Build(postfix)).ToGraphviz()
-		err := os.WriteFile(dfaFileName, []byte(graphvizDFA), 0644)
+		builtNFA, err := buildNFA(tree, *construction)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		builtDFA := dfa.Build(builtNFA)
+		if *complete {
+			builtDFA = builtDFA.Complete()
+		}
+		err = os.WriteFile(dfaFileName, []byte(builtDFA.ToGraphviz()), 0644)
 		if err != nil {
 			fmt.Println("ошибка при записи файла:", err)
 			return
 		}
 		fmt.Printf("DFA сохранен в файл: %s\n", dfaFileName)
+		fmt.Printf("Состояний: %d\n", len(builtDFA.States))
 	case "minDFA":
-		postfix := infixToPostix.Transform(*regex)
-		graphvizMinDFA := dfa.Build(nfa.Build(postfix)).Minimize().ToGraphviz()
-		err := os.WriteFile(minDFAFileName, []byte(graphvizMinDFA), 0644)
+		minDFA, err := minimize(dfa.Build(dfa.CompileNFA(tree)), *strategy)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if *complete {
+			minDFA = minDFA.Complete()
+		}
+		err = os.WriteFile(minDFAFileName, []byte(minDFA.ToGraphviz()), 0644)
 		if err != nil {
 			fmt.Println("ошибка при записи файла:", err)
 			return
 		}
 		fmt.Printf("Min DFA сохранен в файл: %s\n", minDFAFileName)
-	case "modeling":
-		postfix := infixToPostix.Transform(*regex)
-		minDFA := dfa.Build(nfa.Build(postfix)).Minimize()
-		steps, accepted := minDFA.SimulateDFA(*input)
+	case "minDFASteps":
+		builtDFA := dfa.Build(dfa.CompileNFA(tree))
+		stages := builtDFA.MinimizeStages()
+
+		frames := make([]string, len(stages))
+		for i, stage := range stages {
+			frames[i] = stage.ToGraphviz()
+		}
+		if err := writeSteps(frames); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		fmt.Printf("Исходный ДКА: состояний %d\n", len(builtDFA.States))
+		for _, stage := range stages {
+			fmt.Printf("%s: состояний %d\n", stage.Caption, stage.States())
+		}
 
-		err := prepareStepsDir(stepsDir)
+		minDFA := stages[len(stages)-1].DFA
+		err := os.WriteFile(minDFAFileName, []byte(minDFA.ToGraphviz()), 0644)
+		if err != nil {
+			fmt.Println("ошибка при записи файла:", err)
+			return
+		}
+		fmt.Printf("Min DFA сохранен в файл: %s\n", minDFAFileName)
+	case "modeling":
+		minDFA, err := minimize(dfa.Build(dfa.CompileNFA(tree)), *strategy)
 		if err != nil {
-			fmt.Printf("ошибка подготовки папки: %v\n", err)
+			fmt.Println(err)
 			return
 		}
+		if *complete {
+			minDFA = minDFA.Complete()
+		}
+		steps, accepted := minDFA.SimulateDFA(*input)
 
-		for i, step := range steps {
-			filename := fmt.Sprintf(stepsDir+"/step_%d.dot", i+1)
-			err := os.WriteFile(filename, []byte(step), 0644)
-			if err != nil {
-				fmt.Printf("ошибка при записи файла %s: %v\n", filename, err)
-				return
-			}
-			fmt.Printf("Step %d сохранен как %s\n", i+1, filename)
+		if err := writeSteps(steps); err != nil {
+			fmt.Println(err)
+			return
 		}
 
 		if accepted {
@@ -116,7 +266,171 @@ func main() {
 		} else {
 			fmt.Printf("Строка %s НЕ допускается ДКА", *input)
 		}
+	case "nfaModeling":
+		builtNFA, err := buildNFA(tree, *construction)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		steps, accepted := builtNFA.Simulate(*input)
+
+		if err := writeSteps(steps); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if accepted {
+			fmt.Printf("Строка %s допускается НКА", *input)
+		} else {
+			fmt.Printf("Строка %s НЕ допускается НКА", *input)
+		}
+	case "search":
+		minDFA, err := minimize(dfa.Build(dfa.CompileNFA(tree)), *strategy)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		runes := []rune(*input)
+
+		count := 0
+		for match := range minDFA.FindAll(*input) {
+			count++
+			fmt.Printf("Совпадение %d: [%d, %d) %q\n", count, match.Start, match.End, string(runes[match.Start:match.End]))
+		}
+
+		if count == 0 {
+			fmt.Printf("В строке %s совпадений нет\n", *input)
+		}
+	case "equiv":
+		if *regex2 == "" {
+			fmt.Println("в режиме equiv нужно второе выражение: -regex2 <выражение> (язык из пустой строки задается как ε)")
+			return
+		}
+		tree2, err := ast.ParseWithFlags(*regex2, regexFlags)
+		if err != nil {
+			printRegexError(*regex2, err)
+			return
+		}
+
+		dfa1 := dfa.Build(dfa.CompileNFA(tree))
+		dfa2 := dfa.Build(dfa.CompileNFA(tree2))
+		equivalent, counterexample := dfa.Equivalent(dfa1, dfa2)
+		if equivalent {
+			fmt.Printf("Выражения %s и %s эквивалентны\n", *regex, *regex2)
+			return
+		}
+
+		accepting := *regex
+		if dfa2.Accepts(counterexample) {
+			accepting = *regex2
+		}
+		fmt.Printf("Выражения %s и %s НЕ эквивалентны: строку %q допускает только %s\n", *regex, *regex2, counterexample, accepting)
+	case "analyze":
+		compiled := dfa.Compile(tree)
+
+		fmt.Printf("Язык пуст: %s\n", yesNo(compiled.IsEmpty()))
+		fmt.Printf("Язык конечен: %s\n", yesNo(compiled.IsFinite()))
+		fmt.Printf("Строк длины %d: %s\n", *length, compiled.Count(*length))
+		fmt.Printf("Первые %d строк в порядке shortlex:\n", *limit)
+		for _, word := range compiled.Enumerate(*limit) {
+			fmt.Printf("  %q\n", word)
+		}
+	case "followpos":
+		table, err := dfa.Followpos(tree)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Print(table)
+
+		directDFA, err := dfa.BuildDirect(tree)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		err = os.WriteFile(dfaFileName, []byte(directDFA.ToGraphviz()), 0644)
+		if err != nil {
+			fmt.Println("ошибка при записи файла:", err)
+			return
+		}
+		fmt.Printf("DFA сохранен в файл: %s\n", dfaFileName)
+	case "derivativeDFA":
+		derivativeDFA, derivatives := derivative.BuildDFA(tree)
+		for id, r := range derivatives {
+			fmt.Printf("Состояние %d: %s\n", id, r)
+		}
+
+		err := os.WriteFile(dfaFileName, []byte(derivativeDFA.ToGraphviz()), 0644)
+		if err != nil {
+			fmt.Println("ошибка при записи файла:", err)
+			return
+		}
+		fmt.Printf("DFA сохранен в файл: %s\n", dfaFileName)
+	case "lazy":
+		lazyDFA := dfa.NewLazy(dfa.CompileNFA(tree), *cacheSize)
+		if lazyDFA.Accepts(*input) {
+			fmt.Printf("Строка %s допускается ДКА\n", *input)
+		} else {
+			fmt.Printf("Строка %s НЕ допускается ДКА\n", *input)
+		}
+		fmt.Printf("Состояний в кеше: %d, сбросов кеша: %d\n", lazyDFA.CachedStates(), lazyDFA.Flushes())
+	case "captures":
+		mode, err := captureMode(*semantics)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		program, err := pikevm.Compile(tree)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		groups, ok := program.Find(*input, mode)
+		if !ok {
+			fmt.Printf("В строке %s совпадений нет\n", *input)
+			return
+		}
+		runes := []rune(*input)
+		for i, group := range groups {
+			if group.Start < 0 {
+				fmt.Printf("Группа %d: не участвует\n", i)
+				continue
+			}
+			fmt.Printf("Группа %d: [%d, %d) %q\n", i, group.Start, group.End, string(runes[group.Start:group.End]))
+		}
+	case "subsetTrace":
+		builtNFA, err := buildNFA(tree, *construction)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		builtDFA, trace := dfa.BuildWithTrace(builtNFA)
+
+		switch *format {
+		case "markdown":
+			fmt.Print(trace.Markdown())
+		case "latex":
+			fmt.Print(trace.LaTeX())
+		default:
+			fmt.Printf("неизвестный формат %q (доступны markdown, latex)\n", *format)
+			return
+		}
+
+		if err := writeSteps(trace.Frames()); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		err = os.WriteFile(dfaFileName, []byte(builtDFA.ToGraphvizWithSets()), 0644)
+		if err != nil {
+			fmt.Println("ошибка при записи файла:", err)
+			return
+		}
+		fmt.Printf("DFA сохранен в файл: %s\n", dfaFileName)
+	case "toRegex":
+		fmt.Printf("Выражение по минимальному ДКА: %s\n", dfa.Compile(tree).ToRegex())
 	default:
-		fmt.Println("Режим не поддерживается. Доступные режим: nfa, dfa, minDFA, modeling")
+		fmt.Println("Режим не поддерживается. Доступные режим: ast, nfa, dfa, minDFA, modeling, nfaModeling, search, equiv, analyze, toRegex, followpos, derivativeDFA, lazy, captures, subsetTrace, minDFASteps")
 	}
 }
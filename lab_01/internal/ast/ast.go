@@ -0,0 +1,353 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+)
+
+const (
+	NodeLiteral = iota
+	NodeClass
+	NodeAny
+	NodeEmpty
+	NodeBegin
+	NodeEnd
+	NodeConcat
+	NodeAlt
+	NodeAnd
+	NodeNot
+	NodeStar
+	NodePlus
+	NodeOptional
+	NodeRepeat
+	NodeGroup
+	NodeFlags
+)
+
+var nodeNames = map[int]string{
+	NodeLiteral:  "Literal",
+	NodeClass:    "Class",
+	NodeAny:      "Any",
+	NodeEmpty:    "Empty",
+	NodeBegin:    "Begin",
+	NodeEnd:      "End",
+	NodeConcat:   "Concat",
+	NodeAlt:      "Alt",
+	NodeAnd:      "And",
+	NodeNot:      "Not",
+	NodeStar:     "Star",
+	NodePlus:     "Plus",
+	NodeOptional: "Optional",
+	NodeRepeat:   "Repeat",
+	NodeGroup:    "Group",
+	NodeFlags:    "Flags",
+}
+
+// Node - узел дерева регулярного выражения. Какие поля заполнены, зависит от Type:
+// Symbol у Literal, Class у Class, Min и Max у Repeat; у Concat и Alt детей
+// сколько угодно, у And тоже, у квантификаторов, Not и Group - ровно один. Flags - действующие
+// флаги у Literal и Class, FlagsOn и FlagsOff - флаги из записи (?i) (узел Flags
+// без детей) или (?i:...) (Group). Capture - номер захватывающей группы (...) по
+// порядку открывающих скобок, у (?флаги:...) он 0.
+type Node struct {
+	Type     int
+	Pos      int
+	Symbol   rune
+	Class    charclass.Class
+	Min      int
+	Max      int
+	Flags    int
+	FlagsOn  int
+	FlagsOff int
+	Capture  int
+	Children []*Node
+}
+
+func (n *Node) Name() string {
+	return nodeNames[n.Type]
+}
+
+func (n *Node) value() string {
+	switch n.Type {
+	case NodeLiteral:
+		return charclass.QuoteRune(n.Symbol)
+	case NodeClass:
+		return n.Class.String()
+	case NodeAny:
+		return "."
+	case NodeEmpty:
+		return "ε"
+	case NodeBegin:
+		return "^"
+	case NodeEnd:
+		return "$"
+	case NodeNot:
+		return "!"
+	case NodeStar:
+		return "*"
+	case NodePlus:
+		return "+"
+	case NodeOptional:
+		return "?"
+	case NodeRepeat:
+		return repeatString(n.Min, n.Max)
+	case NodeFlags:
+		return "(?" + flagsString(n.FlagsOn, n.FlagsOff) + ")"
+	}
+	return ""
+}
+
+func flagsString(on, off int) string {
+	result := ""
+	if on&lexer.FlagIgnoreCase != 0 {
+		result += "i"
+	}
+	if off&lexer.FlagIgnoreCase != 0 {
+		result += "-i"
+	}
+	return result
+}
+
+// EffectiveClass возвращает класс, по которому на самом деле проходит Literal
+// или Class с учетом флага (?i).
+func (n *Node) EffectiveClass() charclass.Class {
+	class := n.Class
+	if n.Type == NodeLiteral {
+		class = charclass.Class{Ranges: []charclass.Range{{Lo: n.Symbol, Hi: n.Symbol}}}
+	}
+	if n.Flags&lexer.FlagIgnoreCase != 0 {
+		class = class.Fold()
+	}
+	return class
+}
+
+func repeatString(min, max int) string {
+	switch max {
+	case min:
+		return fmt.Sprintf("{%d}", min)
+	case lexer.Unbounded:
+		return fmt.Sprintf("{%d,}", min)
+	}
+	return fmt.Sprintf("{%d,%d}", min, max)
+}
+
+func isQuantifier(n *Node) bool {
+	return n.Type == NodeStar || n.Type == NodePlus || n.Type == NodeOptional || n.Type == NodeRepeat
+}
+
+func precedence(n *Node) int {
+	switch {
+	case n.Type == NodeAlt:
+		return 1
+	case n.Type == NodeAnd:
+		return 2
+	case n.Type == NodeConcat:
+		return 3
+	case n.Type == NodeNot:
+		return 4
+	case isQuantifier(n):
+		return 5
+	}
+	return 6
+}
+
+// IsExtended сообщает, есть ли в выражении пересечение или дополнение: такие
+// выражения строятся не по Томпсону, а через операции над ДКА.
+func IsExtended(n *Node) bool {
+	if n.Type == NodeAnd || n.Type == NodeNot {
+		return true
+	}
+	for _, child := range n.Children {
+		if IsExtended(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// Alphabet разбивает символы, явно упомянутые в выражении (с вариантами
+// регистра под (?i)), на интервалы charclass.NewAlphabet: отрицание класса должно
+// проходить по ним всем, кроме исключенных, и по charclass.Other.
+func Alphabet(node *Node) charclass.Alphabet {
+	classes := []charclass.Class{}
+
+	var traverse func(node *Node)
+	traverse = func(node *Node) {
+		if node.Type == NodeLiteral || node.Type == NodeClass {
+			classes = append(classes, node.EffectiveClass())
+		}
+
+		for _, child := range node.Children {
+			traverse(child)
+		}
+	}
+	traverse(node)
+
+	return charclass.NewAlphabet(classes...)
+}
+
+// operand записывает ребенка, при необходимости беря его в скобки, чтобы
+// приоритет операторов при повторном разборе не изменился.
+func operand(child *Node, minPrecedence int) string {
+	if precedence(child) < minPrecedence {
+		return "(" + child.String() + ")"
+	}
+	return child.String()
+}
+
+// String возвращает выражение в каноническом синтаксисе, который принимает Parse.
+func (n *Node) String() string {
+	switch n.Type {
+	case NodeConcat:
+		var result strings.Builder
+		for _, child := range n.Children {
+			result.WriteString(operand(child, 3))
+		}
+		return result.String()
+	case NodeAlt, NodeAnd:
+		separator, minPrecedence := "|", 1
+		if n.Type == NodeAnd {
+			separator, minPrecedence = "&", 2
+		}
+		branches := make([]string, 0, len(n.Children))
+		for _, child := range n.Children {
+			branches = append(branches, operand(child, minPrecedence))
+		}
+		return strings.Join(branches, separator)
+	case NodeNot:
+		return "!" + operand(n.Children[0], 4)
+	case NodeGroup:
+		if n.FlagsOn != 0 || n.FlagsOff != 0 {
+			return "(?" + flagsString(n.FlagsOn, n.FlagsOff) + ":" + n.Children[0].String() + ")"
+		}
+		if n.Capture == 0 {
+			return "(?:" + n.Children[0].String() + ")"
+		}
+		return "(" + n.Children[0].String() + ")"
+	}
+
+	if isQuantifier(n) {
+		return operand(n.Children[0], 5) + n.value()
+	}
+
+	return n.value()
+}
+
+// Postfix возвращает постфиксную запись в формате Transform: конкатенация
+// обозначается точкой, любой символ - классом [^], служебные символы в
+// операндах экранируются, дополнение записывается после операнда. Флагов в
+// постфиксной записи нет, поэтому символы без учета регистра раскрываются в
+// классы, а (?i) записывается как ε.
+func (n *Node) Postfix() string {
+	var result strings.Builder
+	n.writePostfix(&result)
+	return result.String()
+}
+
+func (n *Node) writePostfix(builder *strings.Builder) {
+	switch n.Type {
+	case NodeConcat, NodeAlt, NodeAnd:
+		operator := "."
+		children := n.Children
+		switch n.Type {
+		case NodeAlt:
+			operator = "|"
+		case NodeAnd:
+			operator = "&"
+		default:
+			children = withoutFlags(children)
+		}
+		for i, child := range children {
+			child.writePostfix(builder)
+			if i > 0 {
+				builder.WriteString(operator)
+			}
+		}
+	case NodeGroup:
+		n.Children[0].writePostfix(builder)
+	case NodeAny:
+		builder.WriteString("[^]")
+	case NodeFlags:
+		builder.WriteString("ε")
+	case NodeLiteral, NodeClass:
+		if n.Flags&lexer.FlagIgnoreCase == 0 {
+			builder.WriteString(n.value())
+			break
+		}
+		class := n.EffectiveClass()
+		if len(class.Ranges) == 1 && class.Ranges[0].Lo == class.Ranges[0].Hi && !class.Negated {
+			builder.WriteString(charclass.QuoteRune(class.Ranges[0].Lo))
+			break
+		}
+		builder.WriteString(class.String())
+	default:
+		for _, child := range n.Children {
+			child.writePostfix(builder)
+		}
+		builder.WriteString(n.value())
+	}
+}
+
+// withoutFlags убирает из конкатенации узлы Flags: они не сопоставляются ни с
+// одним символом. Если кроме них ничего нет, остается один из них.
+func withoutFlags(children []*Node) []*Node {
+	result := []*Node{}
+	for _, child := range children {
+		if child.Type != NodeFlags {
+			result = append(result, child)
+		}
+	}
+	if len(result) == 0 {
+		return children[:1]
+	}
+	return result
+}
+
+func (n *Node) ToDot() string {
+	var builder strings.Builder
+	builder.WriteString("digraph AST {\n")
+	builder.WriteString("  node [shape=box, fontname=\"Courier\", fontsize=10];\n")
+	builder.WriteString("  edge [fontname=\"Courier\", fontsize=10];\n\n")
+
+	var nodeCounter int
+	generateDOTNode(&builder, n, &nodeCounter)
+
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+func generateDOTNode(builder *strings.Builder, node *Node, counter *int) int {
+	if node == nil {
+		return -1
+	}
+
+	currentID := *counter
+	*counter++
+
+	label := node.Name()
+	switch node.Type {
+	case NodeLiteral, NodeClass, NodeRepeat, NodeFlags:
+		label += "\\n" + charclass.EscapeDOT(node.value())
+	case NodeGroup:
+		if node.FlagsOn != 0 || node.FlagsOff != 0 {
+			label += "\\n" + flagsString(node.FlagsOn, node.FlagsOff)
+		}
+		if node.Capture != 0 {
+			label += fmt.Sprintf("\\n#%d", node.Capture)
+		}
+	}
+
+	builder.WriteString(fmt.Sprintf("  node%d [label=\"%s\"];\n", currentID, label))
+
+	for _, child := range node.Children {
+		childID := generateDOTNode(builder, child, counter)
+		if childID >= 0 {
+			builder.WriteString(fmt.Sprintf("  node%d -> node%d;\n", currentID, childID))
+		}
+	}
+
+	return currentID
+}
@@ -0,0 +1,239 @@
+package ast
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		canonical string
+		postfix   string
+	}{
+		{"(ab)*c", "(ab)*c", "ab.*c."},
+		{"a.b", "a.b", "a[^].b."},
+		{".*\\.", ".*\\.", "[^]*\\.."},
+		{"((a))", "((a))", "a"},
+		{"a|bc|d", "a|bc|d", "abc.|d|"},
+		{"[a-z][a-z0-9_]*", "[a-z][a-z0-9_]*", "[a-z][a-z0-9_]*."},
+		{`\*\.\n`, `\*\.\n`, `\*\..\n.`},
+		{"x{2,}y{3}z{0,1}", "x{2,}y{3}z{0,1}", "x{2,}y{3}.z{0,1}."},
+		{"a**", "a**", "a**"},
+		{"(αβ)+|[^\"]", "(αβ)+|[^\"]", "αβ.+[^\"]|"},
+		{"(a|)b", "(a|ε)b", "aε|b."},
+		{"()", "(ε)", "ε"},
+		{"", "ε", "ε"},
+		{"a|ε", "a|ε", "aε|"},
+		{"|a|", "ε|a|ε", "εa|ε|"},
+		{`\εε`, `\εε`, `\εε.`},
+		{"^ab$", "^ab$", "^a.b.$."},
+		{`\^a\$`, `\^a\$`, `\^a.\$.`},
+		{"(?i:ab)c", "(?i:ab)c", "[Aa][Bb].c."},
+		{"(?i)a|b", "(?i)a|b", "[Aa][Bb]|"},
+		{"a(?i)1[^x]", "a(?i)1[^x]", "a1.[^Xx]."},
+		{"(?i)((?-i)a)b", "(?i)((?-i)a)b", "a[Bb]."},
+		{"(?i)", "(?i)", "ε"},
+		{"(?i)^ab", "(?i)^ab", "^[Aa].[Bb]."},
+		{"[a-z]+&!(if|else)", "[a-z]+&!(if|else)", "[a-z]+if.el.s.e.|!&"},
+		{"a|b&c", "a|b&c", "abc&|"},
+		{"!a*b", "!a*b", "a*!b."},
+		{"!!a", "!!a", "a!!"},
+		{`a\&\!`, `a\&\!`, `a\&.\!.`},
+		{"(?:ab)*(c)", "(?:ab)*(c)", "ab.*c."},
+	}
+
+	for _, tt := range tests {
+		node, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Input: %s, неожиданная ошибка: %v", tt.input, err)
+			continue
+		}
+		if node.String() != tt.canonical {
+			t.Errorf("Input: %s, Expected: %s, Actual: %s", tt.input, tt.canonical, node.String())
+		}
+		if node.Postfix() != tt.postfix {
+			t.Errorf("Input: %s, Expected postfix: %s, Actual: %s", tt.input, tt.postfix, node.Postfix())
+		}
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	node, err := Parse("a(b|c)*")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	if node.Type != NodeConcat || len(node.Children) != 2 {
+		t.Fatalf("ожидалась конкатенация из 2 элементов, получено %s с %d детьми", node.Name(), len(node.Children))
+	}
+
+	star := node.Children[1]
+	if star.Type != NodeStar || star.Pos != 1 {
+		t.Errorf("ожидалась звезда на позиции 1, получено %s на позиции %d", star.Name(), star.Pos)
+	}
+
+	group := star.Children[0]
+	if group.Type != NodeGroup || group.Children[0].Type != NodeAlt {
+		t.Errorf("ожидалась группа с альтернативой, получено %s", group.Name())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+		reason string
+	}{
+		{"(ab", 0, lexer.ReasonUnbalancedParen},
+		{"*a", 0, lexer.ReasonDanglingOperator},
+		{"a|*", 2, lexer.ReasonDanglingOperator},
+		{"a!", 1, lexer.ReasonDanglingOperator},
+		{"!*a", 0, lexer.ReasonDanglingOperator},
+		{"a^b", 1, "якорь допустим только в начале или в конце выражения"},
+		{"(^a)", 1, "якорь допустим только в начале или в конце выражения"},
+		{"^a|b$", 0, "якорь допустим только в начале или в конце выражения"},
+		{"a$*", 1, "якорь допустим только в начале или в конце выражения"},
+		{"ab)", 2, lexer.ReasonUnbalancedParen},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+
+		var syntaxErr *lexer.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Input: %s, ожидалась синтаксическая ошибка, получено: %v", tt.input, err)
+			continue
+		}
+		if syntaxErr.Offset != tt.offset || syntaxErr.Reason != tt.reason {
+			t.Errorf("Input: %s, Expected: %d %s, Actual: %d %s", tt.input, tt.offset, tt.reason, syntaxErr.Offset, syntaxErr.Reason)
+		}
+	}
+}
+
+func TestParseWithFlags(t *testing.T) {
+	node, err := ParseWithFlags("a(?-i:b)", lexer.FlagIgnoreCase)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	if actual := node.Postfix(); actual != "[Aa]b." {
+		t.Errorf("Expected: [Aa]b., Actual: %s", actual)
+	}
+}
+
+func TestAnchors(t *testing.T) {
+	tests := []struct {
+		input string
+		begin bool
+		end   bool
+	}{
+		{"ab", false, false},
+		{"^ab", true, false},
+		{"ab$", false, true},
+		{"^(a|b)*$", true, true},
+		{"^", true, false},
+		{"(?i)^a$", true, true},
+	}
+
+	for _, tt := range tests {
+		node, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Input: %s, неожиданная ошибка: %v", tt.input, err)
+			continue
+		}
+		begin, end := Anchors(node)
+		if begin != tt.begin || end != tt.end {
+			t.Errorf("Input: %s, Expected: %v %v, Actual: %v %v", tt.input, tt.begin, tt.end, begin, end)
+		}
+	}
+
+	node, err := FromPostfix("^a.b.$.")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if begin, end := Anchors(node); !begin || !end {
+		t.Errorf("Input: ^a.b.$., ожидались оба якоря, получено: %v %v", begin, end)
+	}
+}
+
+func TestFromPostfix(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ab.*c.", "(ab)*c"},
+		{"abc|*.d.*ad.*c..", "(a(b|c)*d)*(ad)*c"},
+		{"ab|c|", "a|b|c"},
+		{"aε|b.", "(a|ε)b"},
+		{"a*!b.ab|&", "!a*b&(a|b)"},
+		{"ab.!*", "(!(ab))*"},
+		{`\..`, ""},
+		{"ab", ""},
+		{"(a)", ""},
+	}
+
+	for _, tt := range tests {
+		node, err := FromPostfix(tt.input)
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("Input: %s, ожидалась ошибка, получено: %s", tt.input, node)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Input: %s, неожиданная ошибка: %v", tt.input, err)
+			continue
+		}
+		if node.String() != tt.expected {
+			t.Errorf("Input: %s, Expected: %s, Actual: %s", tt.input, tt.expected, node.String())
+		}
+	}
+}
+
+func TestParseCaptures(t *testing.T) {
+	node, err := Parse("((a)(?:b)(?i:c))|(d)")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	captures := []int{}
+	var traverse func(node *Node)
+	traverse = func(node *Node) {
+		if node.Type == NodeGroup {
+			captures = append(captures, node.Capture)
+		}
+		for _, child := range node.Children {
+			traverse(child)
+		}
+	}
+	traverse(node)
+
+	if expected := []int{1, 2, 0, 0, 3}; !reflect.DeepEqual(captures, expected) {
+		t.Errorf("Expected: %v, Actual: %v", expected, captures)
+	}
+}
+
+func TestToDot(t *testing.T) {
+	node, err := Parse(`a"{2}`)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	dot := node.ToDot()
+	for _, expected := range []string{
+		`node0 [label="Concat"];`,
+		`node1 [label="Literal\na"];`,
+		`node2 [label="Repeat\n{2}"];`,
+		`node3 [label="Literal\n\""];`,
+		"node0 -> node2;",
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("ожидалась строка %s, получено:\n%s", expected, dot)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package ast
+
+import (
+	"unicode/utf8"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+)
+
+// Грамматика (по возрастанию приоритета):
+//
+//	alt    -> and ('|' and)*
+//	and    -> concat ('&' concat)*
+//	concat -> unary*
+//	unary  -> '!' unary | repeat
+//	repeat -> atom ('*' | '+' | '?' | '{m,n}')*
+//	atom   -> symbol | class | '.' | 'ε' | '^' | '$' | '(' alt ')' | '(?flags:' alt ')'
+//
+// (?flags) без двоеточия действует до конца объемлющей группы, включая
+// следующие ветки альтернативы; Literal и Class получают действующие флаги.
+// Пустая ветка, пустая группа и пустое выражение означают пустую строку (узел Empty).
+// Якоря ^ и $ допустимы только в начале и в конце всего выражения (см. Anchors).
+type parser struct {
+	tokens   []lexer.Token
+	pos      int
+	end      int
+	flags    int
+	captures int
+}
+
+// Parse разбирает выражение в инфиксной записи. Ошибки возвращаются как *lexer.SyntaxError.
+func Parse(pattern string) (*Node, error) {
+	return ParseWithFlags(pattern, 0)
+}
+
+// ParseWithFlags - как Parse, но флаги (lexer.FlagIgnoreCase и т.д.) действуют
+// на все выражение, как если бы оно начиналось с (?флаги).
+func ParseWithFlags(pattern string, flags int) (*Node, error) {
+	tokens, err := lexer.NewLexer(pattern).TokenizeStrict()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{
+		tokens: tokens,
+		end:    utf8.RuneCountInString(pattern),
+		flags:  flags,
+	}
+
+	node, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.currentToken(); tok.Type != lexer.TokenEOF {
+		return nil, &lexer.SyntaxError{Offset: tok.Pos, Reason: lexer.ReasonUnbalancedParen}
+	}
+
+	if err := checkAnchors(node); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func (p *parser) currentToken() lexer.Token {
+	if p.pos >= len(p.tokens) {
+		return lexer.Token{Type: lexer.TokenEOF, Pos: p.end}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) parseAlt() (*Node, error) {
+	return p.parseBinary(lexer.TokenAlt, NodeAlt, p.parseAnd)
+}
+
+func (p *parser) parseAnd() (*Node, error) {
+	return p.parseBinary(lexer.TokenAnd, NodeAnd, p.parseConcat)
+}
+
+// parseBinary разбирает последовательность операндов через оператор tokenType
+// в один узел nodeType со всеми операндами в качестве детей.
+func (p *parser) parseBinary(tokenType, nodeType int, parseOperand func() (*Node, error)) (*Node, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []*Node{first}
+	for p.currentToken().Type == tokenType {
+		p.pos++
+
+		next, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, next)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return &Node{Type: nodeType, Pos: first.Pos, Children: operands}, nil
+}
+
+func (p *parser) parseConcat() (*Node, error) {
+	items := []*Node{}
+
+	for {
+		tok := p.currentToken()
+
+		switch {
+		case startsAtom(tok) || tok.Type == lexer.TokenNot:
+			node, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, node)
+		case tok.Type == lexer.TokenFlags:
+			p.flags = (p.flags | tok.FlagsOn) &^ tok.FlagsOff
+			items = append(items, &Node{Type: NodeFlags, Pos: tok.Pos, FlagsOn: tok.FlagsOn, FlagsOff: tok.FlagsOff})
+			p.pos++
+		case isQuantifierToken(tok):
+			return nil, &lexer.SyntaxError{Offset: tok.Pos, Reason: lexer.ReasonDanglingOperator}
+		default:
+			switch len(items) {
+			case 0:
+				return &Node{Type: NodeEmpty, Pos: tok.Pos}, nil
+			case 1:
+				return items[0], nil
+			}
+			return &Node{Type: NodeConcat, Pos: items[0].Pos, Children: items}, nil
+		}
+	}
+}
+
+func (p *parser) parseUnary() (*Node, error) {
+	tok := p.currentToken()
+	if tok.Type != lexer.TokenNot {
+		return p.parseRepeat()
+	}
+	p.pos++
+
+	next := p.currentToken()
+	if !startsAtom(next) && next.Type != lexer.TokenNot {
+		return nil, &lexer.SyntaxError{Offset: tok.Pos, Reason: lexer.ReasonDanglingOperator}
+	}
+
+	child, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Type: NodeNot, Pos: tok.Pos, Children: []*Node{child}}, nil
+}
+
+func (p *parser) parseRepeat() (*Node, error) {
+	node, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	for isQuantifierToken(p.currentToken()) {
+		node = quantifierNode(p.currentToken(), node)
+		p.pos++
+	}
+
+	return node, nil
+}
+
+func (p *parser) parseAtom() (*Node, error) {
+	tok := p.currentToken()
+	p.pos++
+
+	if tok.Type != lexer.TokenLParen && tok.Type != lexer.TokenFlagGroup {
+		node := operandNode(tok)
+		node.Flags = p.flags
+		return node, nil
+	}
+
+	// группы нумеруются по открывающей скобке, до разбора вложенных
+	capture := 0
+	if tok.Type == lexer.TokenLParen {
+		p.captures++
+		capture = p.captures
+	}
+
+	// флаги внутри группы не действуют за ее пределами
+	outerFlags := p.flags
+	p.flags = (p.flags | tok.FlagsOn) &^ tok.FlagsOff
+	defer func() { p.flags = outerFlags }()
+
+	inner, err := p.parseAlt()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.currentToken().Type != lexer.TokenRParen {
+		return nil, &lexer.SyntaxError{Offset: tok.Pos, Reason: lexer.ReasonUnbalancedParen}
+	}
+	p.pos++
+
+	return &Node{Type: NodeGroup, Pos: tok.Pos, FlagsOn: tok.FlagsOn, FlagsOff: tok.FlagsOff, Capture: capture, Children: []*Node{inner}}, nil
+}
+
+func operandNode(tok lexer.Token) *Node {
+	switch tok.Type {
+	case lexer.TokenClass:
+		return &Node{Type: NodeClass, Pos: tok.Pos, Class: tok.Class}
+	case lexer.TokenAny:
+		return &Node{Type: NodeAny, Pos: tok.Pos}
+	case lexer.TokenEpsilon:
+		return &Node{Type: NodeEmpty, Pos: tok.Pos}
+	case lexer.TokenBegin:
+		return &Node{Type: NodeBegin, Pos: tok.Pos}
+	case lexer.TokenEnd:
+		return &Node{Type: NodeEnd, Pos: tok.Pos}
+	}
+	return &Node{Type: NodeLiteral, Pos: tok.Pos, Symbol: tok.Symbol}
+}
+
+func isOperandToken(tok lexer.Token) bool {
+	return tok.Type == lexer.TokenSymbol || tok.Type == lexer.TokenClass ||
+		tok.Type == lexer.TokenAny || tok.Type == lexer.TokenEpsilon ||
+		tok.Type == lexer.TokenBegin || tok.Type == lexer.TokenEnd
+}
+
+func startsAtom(tok lexer.Token) bool {
+	return isOperandToken(tok) || tok.Type == lexer.TokenLParen || tok.Type == lexer.TokenFlagGroup
+}
+
+func isQuantifierToken(tok lexer.Token) bool {
+	return tok.Type == lexer.TokenStar || tok.Type == lexer.TokenPlus ||
+		tok.Type == lexer.TokenQuestion || tok.Type == lexer.TokenRepeat
+}
+
+func quantifierNode(tok lexer.Token, child *Node) *Node {
+	node := &Node{Pos: child.Pos, Children: []*Node{child}}
+
+	switch tok.Type {
+	case lexer.TokenStar:
+		node.Type = NodeStar
+	case lexer.TokenPlus:
+		node.Type = NodePlus
+	case lexer.TokenQuestion:
+		node.Type = NodeOptional
+	case lexer.TokenRepeat:
+		node.Type = NodeRepeat
+		node.Min = tok.Min
+		node.Max = tok.Max
+	}
+
+	return node
+}
+
+// FromPostfix восстанавливает дерево по постфиксной записи (формат Transform).
+// Скобок в постфиксной записи нет, поэтому узлов Group в результате тоже нет.
+func FromPostfix(postfix string) (*Node, error) {
+	tokens, err := lexer.NewPostfixLexer(postfix).TokenizeStrict()
+	if err != nil {
+		return nil, err
+	}
+
+	stack := []*Node{}
+
+	for _, tok := range tokens {
+		switch {
+		case tok.Type == lexer.TokenConcat || tok.Type == lexer.TokenAlt || tok.Type == lexer.TokenAnd:
+			if len(stack) < 2 {
+				return nil, &lexer.SyntaxError{Offset: tok.Pos, Reason: lexer.ReasonDanglingOperator}
+			}
+			left := stack[len(stack)-2]
+			right := stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+
+			node := &Node{Type: NodeConcat, Pos: left.Pos, Children: []*Node{left, right}}
+			switch tok.Type {
+			case lexer.TokenAlt:
+				node.Type = NodeAlt
+			case lexer.TokenAnd:
+				node.Type = NodeAnd
+			}
+			stack = append(stack, node)
+		case isQuantifierToken(tok) || tok.Type == lexer.TokenNot:
+			if len(stack) < 1 {
+				return nil, &lexer.SyntaxError{Offset: tok.Pos, Reason: lexer.ReasonDanglingOperator}
+			}
+			if tok.Type == lexer.TokenNot {
+				stack[len(stack)-1] = &Node{Type: NodeNot, Pos: stack[len(stack)-1].Pos, Children: []*Node{stack[len(stack)-1]}}
+				break
+			}
+			stack[len(stack)-1] = quantifierNode(tok, stack[len(stack)-1])
+		case isOperandToken(tok):
+			stack = append(stack, operandNode(tok))
+		default:
+			return nil, &lexer.SyntaxError{Offset: tok.Pos, Reason: "скобки недопустимы в постфиксной записи"}
+		}
+	}
+
+	if len(stack) == 0 {
+		return nil, &lexer.SyntaxError{Offset: 0, Reason: lexer.ReasonEmptyOperand}
+	}
+	if len(stack) > 1 {
+		return nil, &lexer.SyntaxError{Offset: stack[1].Pos, Reason: "операнд без оператора"}
+	}
+
+	if err := checkAnchors(stack[0]); err != nil {
+		return nil, err
+	}
+
+	return stack[0], nil
+}
+
+// Anchors сообщает, привязано ли выражение к началу (^) и к концу ($) текста.
+func Anchors(node *Node) (begin, end bool) {
+	return edgeNode(node, true).Type == NodeBegin, edgeNode(node, false).Type == NodeEnd
+}
+
+// edgeNode спускается по первым (или последним) детям конкатенаций до узла, с
+// которого выражение начинается (или которым заканчивается). Узлы Flags
+// пропускаются, чтобы (?i)^a тоже было привязано к началу.
+func edgeNode(node *Node, first bool) *Node {
+	for node.Type == NodeConcat {
+		children := withoutFlags(node.Children)
+		if first {
+			node = children[0]
+		} else {
+			node = children[len(children)-1]
+		}
+	}
+	return node
+}
+
+func checkAnchors(root *Node) error {
+	begin, end := edgeNode(root, true), edgeNode(root, false)
+
+	var check func(node *Node) error
+	check = func(node *Node) error {
+		if (node.Type == NodeBegin && node != begin) || (node.Type == NodeEnd && node != end) {
+			return &lexer.SyntaxError{Offset: node.Pos, Reason: "якорь допустим только в начале или в конце выражения"}
+		}
+		for _, child := range node.Children {
+			if err := check(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return check(root)
+}
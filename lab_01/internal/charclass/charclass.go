@@ -0,0 +1,371 @@
+package charclass
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Other - служебный символ "любой символ, не встречающийся в выражении явно".
+// Отрицательное значение не пересекается ни с одним символом Unicode.
+const Other rune = -2
+
+// metaChars - символы, которые вне класса нужно экранировать, чтобы они не
+// стали операторами.
+const metaChars = `\()|.*+?{}[]ε^$&!`
+
+type Range struct {
+	Lo rune
+	Hi rune
+}
+
+type Class struct {
+	Negated bool
+	Ranges  []Range
+}
+
+func (c Class) Contains(r rune) bool {
+	if r == Other {
+		return c.Negated
+	}
+
+	inRanges := false
+	for _, rng := range c.Ranges {
+		if r >= rng.Lo && r <= rng.Hi {
+			inRanges = true
+			break
+		}
+	}
+
+	return inRanges != c.Negated
+}
+
+// Runes возвращает символы, явно перечисленные в диапазонах класса (без учета отрицания).
+func (c Class) Runes() []rune {
+	set := make(map[rune]bool)
+	for _, rng := range c.Ranges {
+		for r := rng.Lo; r <= rng.Hi; r++ {
+			set[r] = true
+		}
+	}
+	return sortedRunes(set)
+}
+
+// Symbols возвращает символы алфавита, по которым проходит класс: начала
+// интервалов, лежащих в классе (границы класса совпадают с границами
+// интервалов, см. NewAlphabet). Для отрицания к ним добавляется Other.
+func (c Class) Symbols(alphabet Alphabet) []rune {
+	symbols := []rune{}
+	if c.Negated {
+		symbols = append(symbols, Other)
+	}
+	for _, rng := range alphabet {
+		if rng.Lo != Other && c.Contains(rng.Lo) {
+			symbols = append(symbols, rng.Lo)
+		}
+	}
+	return symbols
+}
+
+// Alphabet - символы автомата: непересекающиеся интервалы рун по возрастанию.
+// Символ записывается началом своего интервала, Other - интервалом
+// {Other, Other} и обозначает все руны вне интервалов.
+type Alphabet []Range
+
+// NewAlphabet разбивает руны, перечисленные в диапазонах классов (без учета
+// отрицания), на наименьшее число интервалов, каждый из которых любой из
+// классов содержит целиком или не содержит совсем.
+func NewAlphabet(classes ...Class) Alphabet {
+	// depth[b] - на сколько меняется число покрывающих диапазонов в точке b
+	depth := make(map[rune]int)
+	for _, class := range classes {
+		for _, rng := range class.Ranges {
+			depth[rng.Lo]++
+			depth[rng.Hi+1]--
+		}
+	}
+
+	boundaries := make([]rune, 0, len(depth))
+	for b := range depth {
+		boundaries = append(boundaries, b)
+	}
+	sort.Slice(boundaries, func(i, j int) bool {
+		return boundaries[i] < boundaries[j]
+	})
+
+	alphabet := Alphabet{}
+	covered := 0
+	for i := 0; i+1 < len(boundaries); i++ {
+		covered += depth[boundaries[i]]
+		if covered > 0 {
+			alphabet = append(alphabet, Range{Lo: boundaries[i], Hi: boundaries[i+1] - 1})
+		}
+	}
+	return alphabet
+}
+
+// Singletons - алфавит из отдельных символов (Other допускается).
+func Singletons(symbols ...rune) Alphabet {
+	set := make(map[rune]bool, len(symbols))
+	for _, symbol := range symbols {
+		set[symbol] = true
+	}
+
+	alphabet := Alphabet{}
+	for _, symbol := range sortedRunes(set) {
+		alphabet = append(alphabet, Range{Lo: symbol, Hi: symbol})
+	}
+	return alphabet
+}
+
+// Refine возвращает общее измельчение явных интервалов двух алфавитов: каждый
+// интервал результата лежит целиком в одном интервале a (или вне всех) и так же
+// для b. Other в результат не входит.
+func Refine(a, b Alphabet) Alphabet {
+	return NewAlphabet(Class{Ranges: a.Explicit()}, Class{Ranges: b.Explicit()})
+}
+
+// Symbols возвращает символы алфавита по возрастанию, Other - первым.
+func (a Alphabet) Symbols() []rune {
+	symbols := make([]rune, len(a))
+	for i, rng := range a {
+		symbols[i] = rng.Lo
+	}
+	return symbols
+}
+
+// Has сообщает, что symbol - символ алфавита (начало интервала или Other).
+func (a Alphabet) Has(symbol rune) bool {
+	i := a.search(symbol)
+	return i < len(a) && a[i].Lo == symbol
+}
+
+// Find возвращает символ, интервал которого содержит руну r, или Other, если r
+// не входит ни в один интервал.
+func (a Alphabet) Find(r rune) rune {
+	i := a.search(r + 1)
+	if i > 0 && a[i-1].Lo != Other && a[i-1].Hi >= r {
+		return a[i-1].Lo
+	}
+	return Other
+}
+
+// Interval возвращает интервал символа; символ вне алфавита - интервал из него одного.
+func (a Alphabet) Interval(symbol rune) Range {
+	if i := a.search(symbol); i < len(a) && a[i].Lo == symbol {
+		return a[i]
+	}
+	return Range{Lo: symbol, Hi: symbol}
+}
+
+// Explicit - алфавит без Other.
+func (a Alphabet) Explicit() Alphabet {
+	if len(a) > 0 && a[0].Lo == Other {
+		return a[1:]
+	}
+	return a
+}
+
+// WithOther - алфавит с Other.
+func (a Alphabet) WithOther() Alphabet {
+	if a.Has(Other) {
+		return a
+	}
+	return append(Alphabet{{Lo: Other, Hi: Other}}, a...)
+}
+
+// search - номер первого интервала, начинающегося не раньше r.
+func (a Alphabet) search(r rune) int {
+	return sort.Search(len(a), func(i int) bool {
+		return a[i].Lo >= r
+	})
+}
+
+// Fold возвращает символ вместе со всеми его вариантами в другом регистре
+// (по unicode.SimpleFold), по возрастанию.
+func Fold(r rune) []rune {
+	set := map[rune]bool{r: true}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		set[f] = true
+	}
+	return sortedRunes(set)
+}
+
+// Fold возвращает класс без учета регистра: в диапазоны добавляются варианты
+// всех перечисленных символов, отрицание сохраняется.
+func (c Class) Fold() Class {
+	set := make(map[rune]bool)
+	for _, r := range c.Runes() {
+		for _, f := range Fold(r) {
+			set[f] = true
+		}
+	}
+
+	folded := Class{Negated: c.Negated}
+	runes := sortedRunes(set)
+	for i := 0; i < len(runes); {
+		j := i
+		for j+1 < len(runes) && runes[j+1] == runes[j]+1 {
+			j++
+		}
+		folded.Ranges = append(folded.Ranges, Range{Lo: runes[i], Hi: runes[j]})
+		i = j + 1
+	}
+
+	return folded
+}
+
+// Representative возвращает печатный символ вне интервалов алфавита: им можно
+// записать Other в примере строки. Если интервалы занимают все печатные
+// символы, возвращается unicode.ReplacementChar.
+func Representative(alphabet Alphabet) rune {
+	for r := 'a'; r <= unicode.MaxRune; r++ {
+		if symbol := alphabet.Find(r); symbol != Other {
+			r = alphabet.Interval(symbol).Hi
+			continue
+		}
+		if unicode.IsPrint(r) {
+			return r
+		}
+	}
+	return unicode.ReplacementChar
+}
+
+func sortedRunes(set map[rune]bool) []rune {
+	runes := make([]rune, 0, len(set))
+	for r := range set {
+		runes = append(runes, r)
+	}
+
+	sort.Slice(runes, func(i, j int) bool {
+		return runes[i] < runes[j]
+	})
+
+	return runes
+}
+
+// Format записывает множество символов компактно: одиночный символ как есть,
+// несколько символов - классом с диапазонами (символ алфавита занимает весь свой
+// интервал), множество с Other - отрицанием относительно алфавита, а весь
+// алфавит вместе с Other - как Σ.
+func Format(symbols []rune, alphabet Alphabet) string {
+	set := make(map[rune]bool)
+	for _, symbol := range symbols {
+		set[symbol] = true
+	}
+
+	if set[Other] {
+		missing := []Range{}
+		for _, rng := range alphabet.Explicit() {
+			if !set[rng.Lo] {
+				missing = append(missing, rng)
+			}
+		}
+		if len(missing) == 0 {
+			return "Σ"
+		}
+		return "[^" + formatRanges(missing) + "]"
+	}
+
+	ranges := make([]Range, 0, len(set))
+	for _, symbol := range sortedRunes(set) {
+		ranges = append(ranges, alphabet.Interval(symbol))
+	}
+	if len(ranges) == 1 && ranges[0].Lo == ranges[0].Hi {
+		return formatRune(ranges[0].Lo, false)
+	}
+	return "[" + formatRanges(ranges) + "]"
+}
+
+// formatRanges записывает интервалы по возрастанию, склеивая соседние: два
+// символа подряд - как есть, три и больше - диапазоном.
+func formatRanges(ranges []Range) string {
+	var result strings.Builder
+
+	for i := 0; i < len(ranges); {
+		lo, hi := ranges[i].Lo, ranges[i].Hi
+		i++
+		for i < len(ranges) && ranges[i].Lo == hi+1 {
+			hi = ranges[i].Hi
+			i++
+		}
+
+		switch hi - lo {
+		case 0:
+			result.WriteString(formatRune(lo, true))
+		case 1:
+			result.WriteString(formatRune(lo, true))
+			result.WriteString(formatRune(hi, true))
+		default:
+			result.WriteString(formatRune(lo, true))
+			result.WriteRune('-')
+			result.WriteString(formatRune(hi, true))
+		}
+	}
+
+	return result.String()
+}
+
+// formatRune записывает символ так же, как его можно написать в выражении:
+// управляющие и непечатаемые символы - escape-последовательностью, а внутри
+// класса дополнительно экранируются ] \ - ^.
+func formatRune(r rune, inClass bool) string {
+	switch r {
+	case '\n':
+		return `\n`
+	case '\t':
+		return `\t`
+	case '\r':
+		return `\r`
+	}
+
+	if !unicode.IsPrint(r) {
+		return fmt.Sprintf("\\u%04X", r)
+	}
+
+	if inClass && strings.ContainsRune(`]\-^`, r) {
+		return `\` + string(r)
+	}
+
+	return string(r)
+}
+
+// QuoteRune записывает одиночный символ в синтаксисе выражения, экранируя служебные.
+func QuoteRune(r rune) string {
+	if strings.ContainsRune(metaChars, r) {
+		return `\` + string(r)
+	}
+	return formatRune(r, false)
+}
+
+func (c Class) String() string {
+	var result strings.Builder
+
+	result.WriteRune('[')
+	if c.Negated {
+		result.WriteRune('^')
+	}
+	for _, rng := range c.Ranges {
+		result.WriteString(formatRune(rng.Lo, true))
+		if rng.Hi != rng.Lo {
+			result.WriteRune('-')
+			result.WriteString(formatRune(rng.Hi, true))
+		}
+	}
+	result.WriteRune(']')
+
+	return result.String()
+}
+
+// Label - то же, что Format, но с экранированием для подписи ребра в Graphviz.
+func Label(symbols []rune, alphabet Alphabet) string {
+	return EscapeDOT(Format(symbols, alphabet))
+}
+
+// EscapeDOT экранирует строку для подстановки в label="..." Graphviz.
+func EscapeDOT(label string) string {
+	label = strings.ReplaceAll(label, "\\", "\\\\")
+	label = strings.ReplaceAll(label, "\"", "\\\"")
+	return label
+}
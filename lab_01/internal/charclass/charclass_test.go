@@ -0,0 +1,171 @@
+package charclass
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		class    Class
+		symbol   rune
+		expected bool
+	}{
+		{Class{Ranges: []Range{{'a', 'z'}}}, 'k', true},
+		{Class{Ranges: []Range{{'a', 'z'}}}, 'K', false},
+		{Class{Ranges: []Range{{'a', 'z'}}}, Other, false},
+		{Class{Negated: true, Ranges: []Range{{'"', '"'}}}, '"', false},
+		{Class{Negated: true, Ranges: []Range{{'"', '"'}}}, 'x', true},
+		{Class{Negated: true, Ranges: []Range{{'"', '"'}}}, Other, true},
+	}
+
+	for _, tt := range tests {
+		actual := tt.class.Contains(tt.symbol)
+		if actual != tt.expected {
+			t.Errorf("Class: %v, Symbol: %c, Expected: %v, Actual: %v", tt.class, tt.symbol, tt.expected, actual)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	tests := []struct {
+		class    Class
+		alphabet Alphabet
+		expected string
+	}{
+		{Class{Ranges: []Range{{'a', 'c'}, {'0', '1'}}}, Singletons([]rune("01abc")...), "01abc"},
+		{Class{Ranges: []Range{{'a', 'c'}, {'0', '1'}}}, Alphabet{{'0', '1'}, {'a', 'c'}, {'x', 'z'}}, "0a"},
+		{Class{Negated: true, Ranges: []Range{{'a', 'b'}}}, Singletons([]rune("abcd")...), string([]rune{Other, 'c', 'd'})},
+		{Class{Negated: true, Ranges: []Range{{'a', 'b'}}}, Alphabet{{'a', 'b'}, {'c', 'f'}}, string([]rune{Other, 'c'})},
+	}
+
+	for _, tt := range tests {
+		actual := string(tt.class.Symbols(tt.alphabet))
+		if actual != tt.expected {
+			t.Errorf("Class: %v, Expected: %q, Actual: %q", tt.class, tt.expected, actual)
+		}
+	}
+}
+
+func TestNewAlphabet(t *testing.T) {
+	tests := []struct {
+		classes  []Class
+		expected Alphabet
+	}{
+		{nil, Alphabet{}},
+		{[]Class{{Ranges: []Range{{'a', 'z'}}}}, Alphabet{{'a', 'z'}}},
+		{[]Class{{Ranges: []Range{{'a', 'f'}}}, {Negated: true, Ranges: []Range{{'d', 'z'}}}}, Alphabet{{'a', 'c'}, {'d', 'f'}, {'g', 'z'}}},
+		{[]Class{{Ranges: []Range{{'a', 'z'}}}, {Ranges: []Range{{'k', 'k'}}}}, Alphabet{{'a', 'j'}, {'k', 'k'}, {'l', 'z'}}},
+		{[]Class{{Ranges: []Range{{'a', 'b'}, {'x', 'y'}}}}, Alphabet{{'a', 'b'}, {'x', 'y'}}},
+		{[]Class{{Ranges: []Range{{'一', '龥'}}}, {Ranges: []Range{{'a', 'a'}}}}, Alphabet{{'a', 'a'}, {'一', '龥'}}},
+	}
+
+	for _, tt := range tests {
+		actual := NewAlphabet(tt.classes...)
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Classes: %v, Expected: %v, Actual: %v", tt.classes, tt.expected, actual)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	alphabet := Alphabet{{Other, Other}, {'a', 'c'}, {'d', 'd'}, {'x', 'z'}}
+	tests := []struct {
+		r        rune
+		expected rune
+	}{
+		{'a', 'a'},
+		{'b', 'a'},
+		{'c', 'a'},
+		{'d', 'd'},
+		{'e', Other},
+		{'y', 'x'},
+		{'0', Other},
+		{Other, Other},
+	}
+
+	for _, tt := range tests {
+		actual := alphabet.Find(tt.r)
+		if actual != tt.expected {
+			t.Errorf("Rune: %q, Expected: %q, Actual: %q", tt.r, tt.expected, actual)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		class    Class
+		expected string
+	}{
+		{Class{Ranges: []Range{{'a', 'c'}}}, "[A-Ca-c]"},
+		{Class{Negated: true, Ranges: []Range{{'x', 'x'}}}, "[^Xx]"},
+		{Class{Ranges: []Range{{'я', 'я'}, {'0', '1'}}}, "[0-1Яя]"},
+		{Class{Ranges: []Range{{'k', 'k'}}}, "[Kk\u212A]"},
+		{Class{Negated: true}, "[^]"},
+	}
+
+	for _, tt := range tests {
+		actual := tt.class.Fold().String()
+		if actual != tt.expected {
+			t.Errorf("Class: %v, Expected: %s, Actual: %s", tt.class, tt.expected, actual)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		symbols  []rune
+		alphabet Alphabet
+		expected string
+	}{
+		{[]rune("a"), nil, "a"},
+		{[]rune("ba"), nil, "[ab]"},
+		{[]rune("_zyxcba9876543210"), nil, "[0-9_a-cx-z]"},
+		{[]rune{Other, 'x'}, Singletons('"', 'x'), "[^\"]"},
+		{[]rune{Other}, Singletons(Other), "Σ"},
+		{[]rune{Other, 'a', 'b'}, Singletons('a', 'b'), "Σ"},
+		{[]rune("a"), Alphabet{{'a', 'z'}}, "[a-z]"},
+		{[]rune("ak"), Alphabet{{'a', 'j'}, {'k', 'k'}, {'l', 'z'}}, "[a-k]"},
+		{[]rune("al"), Alphabet{{'a', 'j'}, {'k', 'k'}, {'l', 'z'}}, "[a-jl-z]"},
+		{[]rune{Other, 'l'}, Alphabet{{'a', 'j'}, {'k', 'k'}, {'l', 'z'}}, "[^a-k]"},
+		{[]rune("\n"), nil, `\n`},
+		{[]rune("*"), nil, "*"},
+		{[]rune("\t\n-]"), nil, `[\t\n\-\]]`},
+		{[]rune{Other}, Singletons('"', '\n'), `[^\n"]`},
+		{[]rune{0x7f}, nil, `\u007F`},
+	}
+
+	for _, tt := range tests {
+		actual := Format(tt.symbols, tt.alphabet)
+		if actual != tt.expected {
+			t.Errorf("Symbols: %q, Expected: %s, Actual: %s", tt.symbols, tt.expected, actual)
+		}
+	}
+}
+
+func TestLabel(t *testing.T) {
+	actual := Label([]rune{Other}, Singletons('"', '\\'))
+	expected := `[^\"\\\\]`
+	if actual != expected {
+		t.Errorf("Expected: %s, Actual: %s", expected, actual)
+	}
+}
+
+func TestRepresentative(t *testing.T) {
+	tests := []struct {
+		alphabet Alphabet
+		expected rune
+	}{
+		{nil, 'a'},
+		{Singletons(Other, 'x'), 'a'},
+		{Singletons([]rune("abd")...), 'c'},
+		{Alphabet{{'a', 'z'}}, '{'},
+	}
+
+	for _, tt := range tests {
+		actual := Representative(tt.alphabet)
+		if actual != tt.expected {
+			t.Errorf("Alphabet: %q, Expected: %c, Actual: %c", tt.alphabet, tt.expected, actual)
+		}
+	}
+}
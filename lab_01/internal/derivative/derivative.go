@@ -0,0 +1,413 @@
+package derivative
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/dfa"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+)
+
+const (
+	kindEmpty   = iota // пустое множество ∅
+	kindEpsilon        // пустая строка ε
+	kindSet            // один символ из множества
+	kindConcat
+	kindStar
+	kindOr
+	kindAnd
+	kindNot
+)
+
+// Regex - выражение в нормальной форме по модулю ACI: вложенные | и & раскрыты,
+// ветки отсортированы по ключу и не повторяются, конкатенация правоассоциативна.
+// Два выражения с одинаковым ключом считаются одним состоянием автомата.
+type Regex struct {
+	kind     int
+	symbols  map[rune]bool      // у kindSet, символы алфавита вместе с charclass.Other
+	alphabet charclass.Alphabet // у kindSet, для записи множества
+	children []*Regex
+	key      string
+}
+
+var (
+	empty   = &Regex{kind: kindEmpty, key: "∅"}
+	epsilon = &Regex{kind: kindEpsilon, key: "ε"}
+	anyStar = not(empty) // Σ*, все строки
+)
+
+// FromAST переводит дерево выражения в Regex. Листья становятся множествами
+// символов алфавита выражения (с charclass.Other), повторы {n,m} раскрываются.
+func FromAST(node *ast.Node) *Regex {
+	return fromAST(node, ast.Alphabet(node))
+}
+
+func fromAST(node *ast.Node, alphabet charclass.Alphabet) *Regex {
+	children := make([]*Regex, len(node.Children))
+	for i, child := range node.Children {
+		if node.Type != ast.NodeRepeat {
+			children[i] = fromAST(child, alphabet)
+		}
+	}
+
+	switch node.Type {
+	case ast.NodeLiteral, ast.NodeClass:
+		return set(node.EffectiveClass().Symbols(alphabet), alphabet)
+	case ast.NodeAny:
+		return set(charclass.Class{Negated: true}.Symbols(alphabet), alphabet)
+	case ast.NodeEmpty, ast.NodeBegin, ast.NodeEnd, ast.NodeFlags:
+		// якоря хранятся в DFA.AnchorStart и DFA.AnchorEnd, флаги уже учтены в листьях
+		return epsilon
+	case ast.NodeConcat:
+		return concat(children...)
+	case ast.NodeAlt:
+		return or(children...)
+	case ast.NodeAnd:
+		return and(children...)
+	case ast.NodeNot:
+		return not(children[0])
+	case ast.NodeStar:
+		return star(children[0])
+	case ast.NodePlus:
+		return concat(children[0], star(children[0]))
+	case ast.NodeOptional:
+		return or(children[0], epsilon)
+	case ast.NodeRepeat:
+		return repeat(fromAST(node.Children[0], alphabet), node.Min, node.Max)
+	}
+
+	// NodeGroup влияет только на разбор
+	return children[0]
+}
+
+// repeat раскрывает r{min,max} в min копий r, за которыми идет (r(r(...)?)?)? из
+// max-min копий или r* при max = lexer.Unbounded.
+func repeat(r *Regex, min, max int) *Regex {
+	tail := star(r)
+	if max != lexer.Unbounded {
+		tail = epsilon
+		for i := min; i < max; i++ {
+			tail = or(concat(r, tail), epsilon)
+		}
+	}
+
+	result := tail
+	for i := 0; i < min; i++ {
+		result = concat(r, result)
+	}
+	return result
+}
+
+func set(symbols []rune, alphabet charclass.Alphabet) *Regex {
+	if len(symbols) == 0 {
+		return empty
+	}
+
+	r := &Regex{kind: kindSet, symbols: make(map[rune]bool, len(symbols)), alphabet: alphabet}
+	for _, symbol := range symbols {
+		r.symbols[symbol] = true
+	}
+
+	parts := []string{}
+	for _, symbol := range r.sortedSymbols() {
+		parts = append(parts, fmt.Sprint(symbol))
+	}
+	r.key = "{" + strings.Join(parts, ",") + "}"
+	return r
+}
+
+func (r *Regex) sortedSymbols() []rune {
+	symbols := make([]rune, 0, len(r.symbols))
+	for symbol := range r.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i] < symbols[j]
+	})
+	return symbols
+}
+
+// concat: ∅r = r∅ = ∅, εr = rε = r, (rs)t = r(st).
+func concat(children ...*Regex) *Regex {
+	result := epsilon
+	for i := len(children) - 1; i >= 0; i-- {
+		result = concat2(children[i], result)
+	}
+	return result
+}
+
+func concat2(left, right *Regex) *Regex {
+	switch {
+	case left.kind == kindEmpty || right.kind == kindEmpty:
+		return empty
+	case left.kind == kindEpsilon:
+		return right
+	case right.kind == kindEpsilon:
+		return left
+	case left.kind == kindConcat:
+		return concat2(left.children[0], concat2(left.children[1], right))
+	}
+	return &Regex{kind: kindConcat, children: []*Regex{left, right}, key: "(" + left.key + "·" + right.key + ")"}
+}
+
+// star: (r*)* = r*, ε* = ∅* = ε.
+func star(child *Regex) *Regex {
+	switch child.kind {
+	case kindEmpty, kindEpsilon:
+		return epsilon
+	case kindStar:
+		return child
+	}
+	return &Regex{kind: kindStar, children: []*Regex{child}, key: "(" + child.key + ")*"}
+}
+
+// not: !!r = r.
+func not(child *Regex) *Regex {
+	if child.kind == kindNot {
+		return child.children[0]
+	}
+	return &Regex{kind: kindNot, children: []*Regex{child}, key: "!(" + child.key + ")"}
+}
+
+// or: ∅ | r = r, Σ* | r = Σ*, множества символов объединяются в одно.
+func or(children ...*Regex) *Regex {
+	return associative(kindOr, children, empty, anyStar)
+}
+
+// and: Σ* & r = r, ∅ & r = ∅, множества символов пересекаются.
+func and(children ...*Regex) *Regex {
+	return associative(kindAnd, children, anyStar, empty)
+}
+
+// associative строит | или & по модулю ACI: вложенные операции того же вида
+// раскрываются (A), ветки сортируются по ключу (C) и не повторяются (I).
+// identity отбрасывается, absorbing поглощает все выражение.
+func associative(kind int, children []*Regex, identity, absorbing *Regex) *Regex {
+	flat := []*Regex{}
+	for _, child := range children {
+		if child.kind == kind {
+			flat = append(flat, child.children...)
+		} else {
+			flat = append(flat, child)
+		}
+	}
+
+	unique := make(map[string]*Regex)
+	var symbols *Regex
+	for _, child := range flat {
+		switch {
+		case child.key == absorbing.key:
+			return absorbing
+		case child.key == identity.key:
+		case child.kind == kindSet:
+			symbols = mergeSets(kind, symbols, child)
+		default:
+			unique[child.key] = child
+		}
+	}
+	if symbols != nil {
+		if symbols.key == absorbing.key {
+			return absorbing
+		}
+		if symbols.key != identity.key {
+			unique[symbols.key] = symbols
+		}
+	}
+
+	keys := make([]string, 0, len(unique))
+	for key := range unique {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch len(keys) {
+	case 0:
+		return identity
+	case 1:
+		return unique[keys[0]]
+	}
+
+	r := &Regex{kind: kind, key: "(" + strings.Join(keys, operators[kind]) + ")"}
+	for _, key := range keys {
+		r.children = append(r.children, unique[key])
+	}
+	return r
+}
+
+var operators = map[int]string{kindOr: "|", kindAnd: "&"}
+
+// mergeSets объединяет (для |) или пересекает (для &) множества символов.
+func mergeSets(kind int, a, b *Regex) *Regex {
+	if a == nil {
+		return b
+	}
+
+	symbols := []rune{}
+	for symbol := range a.symbols {
+		if kind == kindOr || b.symbols[symbol] {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if kind == kindOr {
+		for symbol := range b.symbols {
+			if !a.symbols[symbol] {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return set(symbols, a.alphabet)
+}
+
+// Nullable сообщает, что выражение допускает пустую строку.
+func (r *Regex) Nullable() bool {
+	switch r.kind {
+	case kindEpsilon, kindStar:
+		return true
+	case kindConcat, kindAnd:
+		for _, child := range r.children {
+			if !child.Nullable() {
+				return false
+			}
+		}
+		return true
+	case kindOr:
+		for _, child := range r.children {
+			if child.Nullable() {
+				return true
+			}
+		}
+		return false
+	case kindNot:
+		return !r.children[0].Nullable()
+	}
+	return false
+}
+
+// Derive возвращает производную Бржозовского по символу алфавита (символы вне
+// алфавита передаются как charclass.Other): выражение для остатков строк,
+// начинающихся с symbol.
+func (r *Regex) Derive(symbol rune) *Regex {
+	switch r.kind {
+	case kindSet:
+		if r.symbols[symbol] {
+			return epsilon
+		}
+		return empty
+	case kindConcat:
+		head, tail := r.children[0], r.children[1]
+		result := concat2(head.Derive(symbol), tail)
+		if head.Nullable() {
+			result = or(result, tail.Derive(symbol))
+		}
+		return result
+	case kindStar:
+		return concat2(r.children[0].Derive(symbol), r)
+	case kindOr, kindAnd:
+		derivatives := make([]*Regex, len(r.children))
+		for i, child := range r.children {
+			derivatives[i] = child.Derive(symbol)
+		}
+		if r.kind == kindOr {
+			return or(derivatives...)
+		}
+		return and(derivatives...)
+	case kindNot:
+		return not(r.children[0].Derive(symbol))
+	}
+
+	// производная ∅ и ε - пустое множество
+	return empty
+}
+
+// String записывает выражение для подписей состояний: ∅ - пустое множество,
+// множества символов - как на ребрах ДКА (Σ - любой символ).
+func (r *Regex) String() string {
+	switch r.kind {
+	case kindEmpty, kindEpsilon:
+		return r.key
+	case kindSet:
+		return charclass.Format(r.sortedSymbols(), r.alphabet)
+	case kindConcat:
+		return r.children[0].operand(kindConcat) + r.children[1].operand(kindConcat)
+	case kindStar:
+		return r.children[0].operand(kindStar) + "*"
+	case kindNot:
+		return "!" + r.children[0].operand(kindNot)
+	}
+
+	parts := make([]string, len(r.children))
+	for i, child := range r.children {
+		parts[i] = child.operand(r.kind)
+	}
+	return strings.Join(parts, operators[r.kind])
+}
+
+// precedences - приоритеты как у ast: | < & < конкатенация < ! < *.
+var precedences = map[int]int{kindOr: 1, kindAnd: 2, kindConcat: 3, kindNot: 4, kindStar: 5}
+
+func (r *Regex) operand(parent int) string {
+	if precedence, ok := precedences[r.kind]; ok && precedence <= precedences[parent] && !(r.kind == parent && r.kind == kindConcat) {
+		return "(" + r.String() + ")"
+	}
+	return r.String()
+}
+
+// Match проверяет строку, последовательно беря производные выражения по ее символам.
+func Match(node *ast.Node, input string) bool {
+	alphabet := ast.Alphabet(node)
+	r := fromAST(node, alphabet)
+	for _, symbol := range input {
+		r = r.Derive(alphabet.Find(symbol))
+	}
+	return r.Nullable()
+}
+
+// BuildDFA строит ДКА, состояния которого - различные производные выражения:
+// начальное - само выражение, переход по символу ведет в производную по нему,
+// заключительные - производные, допускающие пустую строку. Производная ∅
+// соответствует отсутствию перехода. Пересечение и дополнение строятся так же,
+// как остальные операции. Вторым значением возвращаются производные по номерам
+// состояний.
+func BuildDFA(node *ast.Node) (*dfa.DFA, []*Regex) {
+	alphabet := ast.Alphabet(node)
+	symbols := alphabet.WithOther().Symbols()
+
+	start := fromAST(node, alphabet)
+	regexes := []*Regex{start}
+	ids := map[string]int{start.key: 0}
+
+	result := &dfa.DFA{
+		States: map[int]*dfa.State{0: dfa.NewState(0, map[int]bool{}, start.Nullable())},
+	}
+	result.AnchorStart, result.AnchorEnd = ast.Anchors(node)
+
+	used := make(map[rune]bool)
+	for id := 0; id < len(regexes); id++ {
+		for _, symbol := range symbols {
+			derivative := regexes[id].Derive(symbol)
+			if derivative.kind == kindEmpty {
+				continue
+			}
+
+			nextStateID, ok := ids[derivative.key]
+			if !ok {
+				nextStateID = len(regexes)
+				ids[derivative.key] = nextStateID
+				regexes = append(regexes, derivative)
+				result.States[nextStateID] = dfa.NewState(nextStateID, map[int]bool{}, derivative.Nullable())
+			}
+			result.States[id].Transitions[symbol] = nextStateID
+			used[symbol] = true
+		}
+	}
+
+	// как у dfa.Build: символы выражения и Other, если по нему есть переход
+	result.Alphabet = alphabet
+	if used[charclass.Other] {
+		result.Alphabet = alphabet.WithOther()
+	}
+	return result, regexes
+}
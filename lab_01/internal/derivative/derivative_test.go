@@ -0,0 +1,103 @@
+package derivative
+
+import (
+	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/dfa"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/regextest"
+)
+
+func parse(t *testing.T, regex string) *ast.Node {
+	node, err := ast.Parse(regex)
+	if err != nil {
+		t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+	}
+	return node
+}
+
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		expected string
+	}{
+		{"abc", "a", "bc"},
+		{"abc", "b", "∅"},
+		{"(a|b)*abb", "a", "[ab]*abb|bb"},
+		{"(a|b)*abb", "ab", "[ab]*abb|b"},
+		{"(a|b)*abb", "abb", "[ab]*abb|ε"},
+		{"a*", "aaa", "a*"},
+		{"a|a|a", "a", "ε"},
+		{"[a-c]&[b-d]", "b", "ε"},
+		{"[a-c]&[b-d]", "a", "∅"},
+		{"!(ab)", "a", "!b"},
+		{"!(ab)", "ab", "!ε"},
+		{"a{2,3}", "a", "a(a|ε)"},
+	}
+
+	for _, tt := range tests {
+		r := FromAST(parse(t, tt.regex))
+		for _, symbol := range tt.input {
+			r = r.Derive(symbol)
+		}
+		if actual := r.String(); actual != tt.expected {
+			t.Errorf("Regex: %s, Input: %s, Expected: %s, Actual: %s", tt.regex, tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		expected bool
+	}{
+		{"(a|b)*abb", "babb", true},
+		{"(a|b)*abb", "abab", false},
+		{"a[^b]c", "aяc", true},
+		{"a[^b]c", "abc", false},
+		{"(?i)ab", "aB", true},
+		{"[a-z]+&!(if|else)", "if", false},
+		{"[a-z]+&!(if|else)", "iff", true},
+		{"!(.*ab.*)", "bbba", true},
+		{"!(.*ab.*)", "bab", false},
+		{"!a", "", true},
+		{"a{2,}", "a", false},
+		{"a{2,}", "aaaa", true},
+		{"ε", "", true},
+	}
+
+	for _, tt := range tests {
+		if actual := Match(parse(t, tt.regex), tt.input); actual != tt.expected {
+			t.Errorf("Regex: %s, Input: %s, Expected: %v, Actual: %v", tt.regex, tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestBuildDFA(t *testing.T) {
+	built, regexes := BuildDFA(parse(t, "(a|b)*abb"))
+	if len(built.States) != 4 || len(regexes) != 4 {
+		t.Fatalf("ожидалось 4 состояния, получено %d", len(built.States))
+	}
+	if regexes[0].String() != "[ab]*abb" || !built.States[3].IsFinal {
+		t.Errorf("неожиданные производные %v", regexes)
+	}
+
+	steps, accepted := built.SimulateDFA("aabb")
+	if !accepted || len(steps) != 6 {
+		t.Errorf("строка aabb должна допускаться за 6 шагов, получено %v и %d шагов", accepted, len(steps))
+	}
+}
+
+func TestBuildDFAMatchesCompile(t *testing.T) {
+	regexes := append(append([]string{}, regextest.Regexes...), regextest.Extended...)
+
+	regextest.MatchesCompile(t, regexes, func(t *testing.T, regex string, node *ast.Node) *dfa.DFA {
+		built, _ := BuildDFA(node)
+		if compiled := dfa.Compile(node); len(built.States) < len(compiled.States) {
+			t.Errorf("Regex: %s, производных %d меньше, чем состояний минимального ДКА %d", regex, len(built.States), len(compiled.States))
+		}
+		return built
+	})
+}
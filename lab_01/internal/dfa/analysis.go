@@ -0,0 +1,264 @@
+package dfa
+
+import (
+	"math/big"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+)
+
+// unicodeScalars - количество символов Unicode без суррогатов, то есть всех
+// рун, которые может содержать строка.
+const unicodeScalars = unicode.MaxRune + 1 - 0x800
+
+// IsEmpty сообщает, что автомат не допускает ни одной строки.
+func (dfa *DFA) IsEmpty() bool {
+	for id := range dfa.reachable() {
+		if dfa.States[id].IsFinal {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFinite сообщает, что язык конечен: среди полезных состояний (достижимых из
+// начального, из которых достижимо заключительное) нет цикла.
+func (dfa *DFA) IsFinite() bool {
+	useful := dfa.usefulStates()
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	color := make(map[int]int, len(useful))
+
+	var hasCycle func(id int) bool
+	hasCycle = func(id int) bool {
+		color[id] = inProgress
+		for _, nextStateID := range dfa.States[id].Transitions {
+			if !useful[nextStateID] {
+				continue
+			}
+			switch color[nextStateID] {
+			case inProgress:
+				return true
+			case unvisited:
+				if hasCycle(nextStateID) {
+					return true
+				}
+			}
+		}
+		color[id] = done
+		return false
+	}
+
+	for id := range useful {
+		if color[id] == unvisited && hasCycle(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// Count возвращает количество допускаемых строк длины length. Переход по символу
+// считается за все символы его интервала, а по charclass.Other - за все символы
+// Unicode вне алфавита, поэтому у выражений с . или [^...] числа получаются
+// очень большими.
+func (dfa *DFA) Count(length int) *big.Int {
+	weights := make(map[rune]*big.Int, len(dfa.Alphabet))
+	other := int64(unicodeScalars)
+	for _, rng := range dfa.Alphabet.Explicit() {
+		weight := scalars(rng)
+		weights[rng.Lo] = big.NewInt(weight)
+		other -= weight
+	}
+	weights[charclass.Other] = big.NewInt(other)
+
+	// counts[id] - число строк текущей длины, допускаемых из состояния id
+	counts := make(map[int]*big.Int, len(dfa.States))
+	for id, state := range dfa.States {
+		counts[id] = big.NewInt(0)
+		if state.IsFinal {
+			counts[id].SetInt64(1)
+		}
+	}
+
+	for step := 0; step < length; step++ {
+		next := make(map[int]*big.Int, len(dfa.States))
+		for id, state := range dfa.States {
+			sum := big.NewInt(0)
+			for symbol, nextStateID := range state.Transitions {
+				weight, ok := weights[symbol]
+				if !ok {
+					weight = big.NewInt(1)
+				}
+				sum.Add(sum, new(big.Int).Mul(weight, counts[nextStateID]))
+			}
+			next[id] = sum
+		}
+		counts = next
+	}
+
+	return counts[dfa.Start]
+}
+
+// scalars - число символов интервала без суррогатов.
+func scalars(rng charclass.Range) int64 {
+	count := int64(rng.Hi - rng.Lo + 1)
+	lo, hi := max(rng.Lo, 0xD800), min(rng.Hi, 0xDFFF)
+	if lo <= hi {
+		count -= int64(hi - lo + 1)
+	}
+	return count
+}
+
+// Enumerate возвращает первые limit допускаемых строк в порядке shortlex (сначала
+// короткие, строки одной длины - по алфавиту). Переход по символу дает все
+// символы его интервала, а все символы вне алфавита неразличимы, поэтому они
+// представлены одним символом (charclass.Representative).
+func (dfa *DFA) Enumerate(limit int) []string {
+	words := []string{}
+	if limit <= 0 || dfa.IsEmpty() {
+		return words
+	}
+
+	finite := dfa.IsFinite()
+	symbols, ranges := dfa.orderedSymbols()
+
+	// canFinish[r][id] - из состояния id можно дойти до заключительного ровно за r шагов
+	canFinish := []map[int]bool{{}}
+	for id, state := range dfa.States {
+		canFinish[0][id] = state.IsFinal
+	}
+
+	var collect func(id int, prefix []rune, remaining int)
+	collect = func(id int, prefix []rune, remaining int) {
+		if len(words) >= limit || !canFinish[remaining][id] {
+			return
+		}
+		if remaining == 0 {
+			words = append(words, string(prefix))
+			return
+		}
+		for i, symbol := range symbols {
+			nextStateID, ok := dfa.States[id].Transitions[symbol]
+			if !ok {
+				continue
+			}
+			for r := ranges[i].Lo; r <= ranges[i].Hi && len(words) < limit; r++ {
+				if !utf8.ValidRune(r) {
+					continue
+				}
+				collect(nextStateID, append(prefix, r), remaining-1)
+			}
+		}
+	}
+
+	for length := 0; len(words) < limit; length++ {
+		// у конечного языка нет строк длиннее числа состояний
+		if finite && length > len(dfa.States) {
+			break
+		}
+
+		if length > 0 {
+			level := make(map[int]bool, len(dfa.States))
+			for id, state := range dfa.States {
+				for _, nextStateID := range state.Transitions {
+					if canFinish[length-1][nextStateID] {
+						level[id] = true
+						break
+					}
+				}
+			}
+			canFinish = append(canFinish, level)
+		}
+
+		collect(dfa.Start, []rune{}, length)
+	}
+
+	return words
+}
+
+// orderedSymbols возвращает символы алфавита в порядке рун, которыми они
+// записываются, вместе с их интервалами: Other стоит на месте своего
+// представителя.
+func (dfa *DFA) orderedSymbols() ([]rune, []charclass.Range) {
+	explicit := dfa.Alphabet.Explicit()
+
+	symbols := explicit.Symbols()
+	if dfa.Alphabet.Has(charclass.Other) {
+		symbols = append(symbols, charclass.Other)
+	}
+
+	representative := charclass.Representative(explicit)
+	rangeOf := func(symbol rune) charclass.Range {
+		if symbol == charclass.Other {
+			return charclass.Range{Lo: representative, Hi: representative}
+		}
+		return explicit.Interval(symbol)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		return rangeOf(symbols[i]).Lo < rangeOf(symbols[j]).Lo
+	})
+
+	ranges := make([]charclass.Range, len(symbols))
+	for i, symbol := range symbols {
+		ranges[i] = rangeOf(symbol)
+	}
+	return symbols, ranges
+}
+
+func (dfa *DFA) reachable() map[int]bool {
+	visited := map[int]bool{dfa.Start: true}
+	stack := []int{dfa.Start}
+
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, nextStateID := range dfa.States[id].Transitions {
+			if !visited[nextStateID] {
+				visited[nextStateID] = true
+				stack = append(stack, nextStateID)
+			}
+		}
+	}
+
+	return visited
+}
+
+// usefulStates - достижимые состояния, из которых достижимо заключительное.
+func (dfa *DFA) usefulStates() map[int]bool {
+	reachable := dfa.reachable()
+
+	predecessors := make(map[int][]int)
+	stack := []int{}
+	useful := make(map[int]bool)
+	for id := range reachable {
+		for _, nextStateID := range dfa.States[id].Transitions {
+			predecessors[nextStateID] = append(predecessors[nextStateID], id)
+		}
+		if dfa.States[id].IsFinal {
+			useful[id] = true
+			stack = append(stack, id)
+		}
+	}
+
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, prev := range predecessors[id] {
+			if !useful[prev] {
+				useful[prev] = true
+				stack = append(stack, prev)
+			}
+		}
+	}
+
+	return useful
+}
@@ -0,0 +1,83 @@
+package dfa
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	nfa_pkg "github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+)
+
+// MinimizeStage - промежуточный автомат алгоритма Бржозовского: задан либо NFA
+// (обращенный автомат), либо DFA (результат детерминизации).
+type MinimizeStage struct {
+	Caption string
+	NFA     *nfa_pkg.NFA
+	DFA     *DFA
+}
+
+// MinimizeStages выполняет минимизацию Бржозовского и возвращает все четыре
+// автомата по порядку: обращенный ДКА, промежуточный ДКА, обращенный
+// промежуточный ДКА и минимальный ДКА. Последний совпадает с Minimize().
+func (dfa *DFA) MinimizeStages() []MinimizeStage {
+	invertedNFA := dfa.invert()
+	intermediateDFA := Build(invertedNFA)
+	invertedNFA2 := intermediateDFA.invert()
+	minimizedDFA := Build(invertedNFA2)
+
+	return []MinimizeStage{
+		{Caption: "Шаг 1: обращение исходного ДКА", NFA: invertedNFA},
+		{Caption: "Шаг 2: детерминизация обращенного автомата", DFA: intermediateDFA},
+		{Caption: "Шаг 3: обращение промежуточного ДКА", NFA: invertedNFA2},
+		{Caption: "Шаг 4: детерминизация, минимальный ДКА", DFA: minimizedDFA},
+	}
+}
+
+// States - число состояний автомата; у обращенного считаются только
+// достижимые из начальных, а если начальных нет (язык пуст), их 0.
+func (stage MinimizeStage) States() int {
+	if stage.NFA != nil {
+		if len(stage.NFA.StartStates) == 0 {
+			return 0
+		}
+		return len(stage.NFA.States())
+	}
+	return len(stage.DFA.States)
+}
+
+// Empty - пуст ли язык автомата. Обращение не меняет пустоту, поэтому для
+// пустого языка пусты все четыре этапа.
+func (stage MinimizeStage) Empty() bool {
+	if stage.NFA != nil {
+		return len(stage.NFA.StartStates) == 0
+	}
+	return stage.DFA.IsEmpty()
+}
+
+// ToGraphviz рисует автомат с подписью этапа и числом состояний. У обращенного
+// автомата пустого языка нет ни начальных состояний, ни переходов, поэтому
+// вместо него рисуется ∅.
+func (stage MinimizeStage) ToGraphviz() string {
+	graph := ""
+	switch {
+	case stage.NFA != nil && stage.Empty():
+		graph = "digraph NFA {\n"
+		graph += "  empty [shape = none, label=\"∅\"];\n"
+		graph += "}\n"
+	case stage.NFA != nil:
+		graph = stage.NFA.ToGraphviz()
+	default:
+		graph = stage.DFA.ToGraphviz()
+	}
+
+	caption := charclass.EscapeDOT(stage.Caption)
+	if stage.Empty() {
+		caption += " (язык пуст)"
+	}
+
+	graph = strings.TrimSuffix(graph, "}\n")
+	graph += "  labelloc=\"t\";\n"
+	graph += fmt.Sprintf("  label=\"%s\\nСостояний: %d\";\n", caption, stage.States())
+	graph += "}\n"
+	return graph
+}
@@ -0,0 +1,68 @@
+package dfa_test
+
+import (
+	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/dfa"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/regextest"
+)
+
+// Тесты этого файла сверяют способы построения ДКА с dfa.Compile на общих
+// выражениях из regextest, поэтому они во внешнем пакете dfa_test.
+
+func TestToRegexRoundTrip(t *testing.T) {
+	regexes := append(append([]string{}, regextest.Regexes...), regextest.Extended...)
+	regexes = append(regexes, ".*x.*", "x\\|y|\\ε")
+
+	regextest.MatchesCompile(t, regexes, func(t *testing.T, regex string, node *ast.Node) *dfa.DFA {
+		converted := dfa.Compile(node).ToRegex().String()
+		reparsed, err := ast.Parse(converted)
+		if err != nil {
+			t.Errorf("Regex: %s, полученное выражение %s не разбирается: %v", regex, converted, err)
+			return nil
+		}
+		return dfa.Compile(reparsed)
+	})
+}
+
+func TestBuildDirectMatchesCompile(t *testing.T) {
+	regextest.MatchesCompile(t, regextest.Regexes, func(t *testing.T, regex string, node *ast.Node) *dfa.DFA {
+		direct, err := dfa.BuildDirect(node)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+
+		anchorStart, anchorEnd := ast.Anchors(node)
+		if direct.AnchorStart != anchorStart || direct.AnchorEnd != anchorEnd {
+			t.Errorf("Regex: %s, якоря не совпадают", regex)
+		}
+		return direct
+	})
+}
+
+// epsilonFree проверяет, что автомат без эпсилон-переходов не больше НКА Томпсона.
+func epsilonFree(t *testing.T, regex, name string, automaton, thompson *nfa.NFA) *dfa.DFA {
+	if len(automaton.States()) > len(thompson.States()) {
+		t.Errorf("Regex: %s, %s: состояний %d больше, чем у НКА Томпсона (%d)", regex, name, len(automaton.States()), len(thompson.States()))
+	}
+	return dfa.Build(automaton)
+}
+
+func TestRemoveEpsilonMatchesCompile(t *testing.T) {
+	regextest.MatchesCompile(t, regextest.Regexes, func(t *testing.T, regex string, node *ast.Node) *dfa.DFA {
+		thompson := nfa.FromAST(node)
+		return epsilonFree(t, regex, "RemoveEpsilon", thompson.RemoveEpsilon(), thompson)
+	})
+}
+
+func TestGlushkovMatchesCompile(t *testing.T) {
+	regextest.MatchesCompile(t, regextest.Regexes, func(t *testing.T, regex string, node *ast.Node) *dfa.DFA {
+		glushkov, err := dfa.Glushkov(node)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+		return epsilonFree(t, regex, "Glushkov", glushkov, nfa.FromAST(node))
+	})
+}
@@ -0,0 +1,73 @@
+package dfa
+
+import (
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	nfa_pkg "github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+)
+
+// Compile строит минимальный ДКА выражения. Пересечение и дополнение строятся
+// произведением и дополнением ДКА операндов, остальное - по Томпсону с
+// подстановкой ДКА для подвыражений с & и ! (см. CompileNFA).
+func Compile(node *ast.Node) *DFA {
+	switch node.Type {
+	case ast.NodeAnd:
+		result := Compile(node.Children[0])
+		for _, child := range node.Children[1:] {
+			result = Intersect(result, Compile(child)).Minimize()
+		}
+		return result
+	case ast.NodeNot:
+		return Complement(Compile(node.Children[0]), nil).Minimize()
+	case ast.NodeGroup:
+		return Compile(node.Children[0])
+	}
+
+	return Build(CompileNFA(node)).Minimize()
+}
+
+// CompileNFA строит НКА Томпсона; подвыражения с & и ! сначала компилируются в
+// ДКА, который затем встраивается в НКА как фрагмент. Для выражений без них
+// результат совпадает с nfa.FromAST.
+func CompileNFA(node *ast.Node) *nfa_pkg.NFA {
+	return nfa_pkg.FromASTWith(node, func(node *ast.Node, alphabet charclass.Alphabet) *nfa_pkg.NFA {
+		return Compile(node).toNFA(alphabet)
+	})
+}
+
+// toNFA превращает автомат во фрагмент НКА с одним заключительным состоянием, в
+// которое ведут эпсилон-переходы из всех заключительных. alphabet мельче
+// алфавита автомата, поэтому переход по символу заменяется переходами по всем
+// интервалам alphabet внутри его интервала, а переход по Other - еще и по
+// интервалам вне алфавита автомата.
+func (dfa *DFA) toNFA(alphabet charclass.Alphabet) *nfa_pkg.NFA {
+	outer := map[rune][]rune{charclass.Other: {charclass.Other}}
+	for _, symbol := range alphabet.Explicit().Symbols() {
+		inner := dfa.Alphabet.Find(symbol)
+		outer[inner] = append(outer[inner], symbol)
+	}
+
+	states := make(map[int]*nfa_pkg.State, len(dfa.States))
+	endID := 0
+	for id := range dfa.States {
+		states[id] = nfa_pkg.NewState(id)
+		if id >= endID {
+			endID = id + 1
+		}
+	}
+	end := nfa_pkg.NewState(endID)
+
+	for id, state := range dfa.States {
+		from := states[id]
+		for symbol, nextStateID := range state.Transitions {
+			for _, s := range outer[symbol] {
+				from.Transitions[s] = append(from.Transitions[s], states[nextStateID])
+			}
+		}
+		if state.IsFinal {
+			from.Transitions[nfa_pkg.EPS] = append(from.Transitions[nfa_pkg.EPS], end)
+		}
+	}
+
+	return nfa_pkg.New(states[dfa.Start], end)
+}
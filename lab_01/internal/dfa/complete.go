@@ -0,0 +1,63 @@
+package dfa
+
+// Complete возвращает копию автомата с всюду определенной функцией переходов:
+// все отсутствующие переходы ведут в одно тупиковое состояние (IsDead), а в
+// алфавит добавляется charclass.Other, чтобы переход был и по символам, не
+// встречающимся в выражении. Если автомат уже полный, тупик не добавляется.
+func (dfa *DFA) Complete() *DFA {
+	alphabet := dfa.Alphabet.WithOther()
+
+	complete := &DFA{
+		Start:       dfa.Start,
+		States:      make(map[int]*State, len(dfa.States)+1),
+		Alphabet:    alphabet,
+		AnchorStart: dfa.AnchorStart,
+		AnchorEnd:   dfa.AnchorEnd,
+	}
+
+	deadID := 0
+	for id, state := range dfa.States {
+		copied := NewState(id, state.NFAStates, state.IsFinal)
+		copied.IsDead = state.IsDead
+		for symbol, nextStateID := range state.Transitions {
+			copied.Transitions[symbol] = nextStateID
+		}
+		complete.States[id] = copied
+
+		if id >= deadID {
+			deadID = id + 1
+		}
+	}
+
+	var dead *State
+	for _, state := range complete.States {
+		for _, symbol := range alphabet.Symbols() {
+			if _, ok := state.Transitions[symbol]; ok {
+				continue
+			}
+			if dead == nil {
+				dead = NewState(deadID, map[int]bool{}, false)
+				dead.IsDead = true
+			}
+			state.Transitions[symbol] = dead.ID
+		}
+	}
+
+	if dead != nil {
+		for _, symbol := range alphabet.Symbols() {
+			dead.Transitions[symbol] = dead.ID
+		}
+		complete.States[dead.ID] = dead
+	}
+
+	return complete
+}
+
+func containsSymbol(alphabet []rune, symbol rune) bool {
+	for _, s := range alphabet {
+		if s == symbol {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,9 @@ package dfa
 
 import (
 	"fmt"
+	"sort"
 
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
 	nfa_pkg "github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
 )
 
@@ -11,12 +13,15 @@ type State struct {
 	NFAStates   map[int]bool
 	Transitions map[rune]int
 	IsFinal     bool
+	IsDead      bool // тупиковое состояние, добавленное Complete
 }
 
 type DFA struct {
-	Start    int
-	States   map[int]*State
-	Alphabet []rune
+	Start       int
+	States      map[int]*State
+	Alphabet    charclass.Alphabet
+	AnchorStart bool
+	AnchorEnd   bool
 }
 
 func NewState(id int, nfaStates map[int]bool, isFinal bool) *State {
@@ -29,11 +34,18 @@ func NewState(id int, nfaStates map[int]bool, isFinal bool) *State {
 }
 
 func Build(nfa *nfa_pkg.NFA) *DFA {
+	return build(nfa, nil)
+}
+
+// build - построение подмножеств; если trace не nil, в него записывается каждый шаг.
+func build(nfa *nfa_pkg.NFA, trace *Trace) *DFA {
 	alphabet := nfa.ExtractAlphabet()
 
 	dfa := &DFA{
-		States:   make(map[int]*State),
-		Alphabet: alphabet,
+		States:      make(map[int]*State),
+		Alphabet:    alphabet,
+		AnchorStart: nfa.AnchorStart,
+		AnchorEnd:   nfa.AnchorEnd,
 	}
 
 	startedStates := make(map[int]bool)
@@ -61,18 +73,19 @@ func Build(nfa *nfa_pkg.NFA) *DFA {
 
 		currentState := dfa.States[currentStateID]
 
-		for _, symbol := range alphabet {
-			nextNFAStates := make(map[int]bool)
+		for _, symbol := range alphabet.Symbols() {
+			moveStates := make(map[int]bool)
 			for nfaStateID := range currentState.NFAStates {
 				state := nfa.StateByID(nfaStateID)
 				for _, nextState := range state.Transitions[symbol] {
-					nextNFAStates[nextState.ID] = true
+					moveStates[nextState.ID] = true
 				}
 			}
 
-			nextNFAStates = nfa.EpsilonClosure(nextNFAStates)
+			nextNFAStates := nfa.EpsilonClosure(moveStates)
 
 			if len(nextNFAStates) == 0 {
+				trace.add(TraceStep{From: currentStateID, Symbol: symbol, To: -1})
 				continue
 			}
 
@@ -94,6 +107,7 @@ func Build(nfa *nfa_pkg.NFA) *DFA {
 			}
 
 			currentState.Transitions[symbol] = nextStateID
+			trace.add(TraceStep{From: currentStateID, Symbol: symbol, Move: sortedIDs(moveStates), Closure: sortedIDs(nextNFAStates), To: nextStateID, New: !found})
 		}
 	}
 
@@ -120,30 +134,67 @@ func (dfa *DFA) ToGraphviz() string {
 	graph += fmt.Sprintf("  start [shape = point];\n")
 	graph += fmt.Sprintf("  start -> %d;\n", dfa.Start)
 
-	for _, state := range dfa.States {
-		if state.IsFinal {
+	graph += dfa.statesToGraphviz()
+
+	graph += dfa.edgesToGraphviz()
+
+	graph += "}\n"
+	return graph
+}
+
+// statesToGraphviz рисует состояния: заключительные двойным кругом, тупиковое -
+// серым пунктиром.
+func (dfa *DFA) statesToGraphviz() string {
+	graph := ""
+
+	stateIDs := make([]int, 0, len(dfa.States))
+	for id := range dfa.States {
+		stateIDs = append(stateIDs, id)
+	}
+	sort.Ints(stateIDs)
+
+	for _, id := range stateIDs {
+		state := dfa.States[id]
+		switch {
+		case state.IsFinal:
 			graph += fmt.Sprintf("  %d [shape = doublecircle];\n", state.ID)
-		} else {
+		case state.IsDead:
+			graph += fmt.Sprintf("  %d [shape = circle, style = dashed, color = gray, fontcolor = gray];\n", state.ID)
+		default:
 			graph += fmt.Sprintf("  %d [shape = circle];\n", state.ID)
 		}
 	}
 
+	return graph
+}
+
+// edgesToGraphviz рисует по одному ребру на пару состояний, подписывая его всеми
+// символами перехода в компактном виде (диапазонами).
+func (dfa *DFA) edgesToGraphviz() string {
+	graph := ""
+
 	for _, state := range dfa.States {
+		symbols := make(map[int][]rune)
+		nextStateIDs := []int{}
 		for symbol, nextStateID := range state.Transitions {
-			graph += fmt.Sprintf("  %d -> %d [label=\"%c\"];\n", state.ID, nextStateID, symbol)
+			if _, ok := symbols[nextStateID]; !ok {
+				nextStateIDs = append(nextStateIDs, nextStateID)
+			}
+			symbols[nextStateID] = append(symbols[nextStateID], symbol)
+		}
+
+		sort.Ints(nextStateIDs)
+		for _, nextStateID := range nextStateIDs {
+			graph += fmt.Sprintf("  %d -> %d [label=\"%s\"];\n", state.ID, nextStateID, charclass.Label(symbols[nextStateID], dfa.Alphabet))
 		}
 	}
 
-	graph += "}\n"
 	return graph
 }
 
 func (dfa *DFA) Minimize() *DFA {
-	invertedNFA := dfa.invert()
-	intermediateDFA := Build(invertedNFA)
-	invertedNFA2 := intermediateDFA.invert()
-	minimizedDFA := Build(invertedNFA2)
-	return minimizedDFA
+	stages := dfa.MinimizeStages()
+	return stages[len(stages)-1].DFA
 }
 
 func (dfa *DFA) invert() *nfa_pkg.NFA {
@@ -173,6 +224,9 @@ func (dfa *DFA) invert() *nfa_pkg.NFA {
 		Start:       stateMap[startRandomID],
 		End:         stateMap[dfa.Start],
 		StartStates: startStates,
+		Alphabet:    dfa.Alphabet,
+		AnchorStart: dfa.AnchorStart,
+		AnchorEnd:   dfa.AnchorEnd,
 	}
 
 	return nfa
@@ -185,11 +239,11 @@ func (dfa *DFA) SimulateDFA(input string) ([]string, bool) {
 
 	steps = append(steps, dfa.ToGraphvizWithHighlight(currentStateID, "Start"))
 
-	for i, symbol := range input {
-		if nextStateID, exists := currentState.Transitions[symbol]; exists {
+	for i, symbol := range []rune(input) {
+		if nextStateID, exists := currentState.Transitions[dfa.alphabetSymbol(symbol)]; exists {
 			currentStateID = nextStateID
 			currentState = dfa.States[currentStateID]
-			steps = append(steps, dfa.ToGraphvizWithHighlight(currentStateID, fmt.Sprintf("Step %d: Symbol '%c'", i+1, symbol)))
+			steps = append(steps, dfa.ToGraphvizWithHighlight(currentStateID, fmt.Sprintf("Step %d: Symbol '%s'", i+1, charclass.Label([]rune{symbol}, nil))))
 		} else {
 			steps = append(steps, dfa.ToGraphvizWithError(currentStateID, symbol))
 			return steps, false
@@ -206,6 +260,26 @@ func (dfa *DFA) SimulateDFA(input string) ([]string, bool) {
 	return steps, isAccepted
 }
 
+// Accepts - то же, что SimulateDFA, но без построения графов для каждого шага.
+func (dfa *DFA) Accepts(input string) bool {
+	state := dfa.States[dfa.Start]
+	for _, symbol := range input {
+		nextStateID, ok := state.Transitions[dfa.alphabetSymbol(symbol)]
+		if !ok {
+			return false
+		}
+		state = dfa.States[nextStateID]
+	}
+	return state.IsFinal
+}
+
+// alphabetSymbol сопоставляет входному символу символ алфавита - начало
+// интервала, в который он входит: все символы, не встречающиеся в выражении
+// явно, неразличимы и переходят по charclass.Other.
+func (dfa *DFA) alphabetSymbol(r rune) rune {
+	return dfa.Alphabet.Find(r)
+}
+
 func (dfa *DFA) ToGraphvizWithHighlight(currentStateID int, description string) string {
 	graph := "digraph DFA {\n"
 	graph += "  rankdir=LR;\n"
@@ -214,24 +288,14 @@ func (dfa *DFA) ToGraphvizWithHighlight(currentStateID int, description string)
 	graph += fmt.Sprintf("  start [shape = point];\n")
 	graph += fmt.Sprintf("  start -> %d;\n", dfa.Start)
 
-	for _, state := range dfa.States {
-		if state.IsFinal {
-			graph += fmt.Sprintf("  %d [shape = doublecircle];\n", state.ID)
-		} else {
-			graph += fmt.Sprintf("  %d [shape = circle];\n", state.ID)
-		}
-	}
+	graph += dfa.statesToGraphviz()
 
 	graph += fmt.Sprintf("  %d [color=red, fontcolor=red];\n", currentStateID)
 
 	graph += fmt.Sprintf("  labelloc=\"t\";\n")
 	graph += fmt.Sprintf("  label=\"%s\";\n", description)
 
-	for _, state := range dfa.States {
-		for symbol, nextStateID := range state.Transitions {
-			graph += fmt.Sprintf("  %d -> %d [label=\"%c\"];\n", state.ID, nextStateID, symbol)
-		}
-	}
+	graph += dfa.edgesToGraphviz()
 
 	graph += "}\n"
 	return graph
@@ -245,26 +309,17 @@ func (dfa *DFA) ToGraphvizWithError(currentStateID int, symbol rune) string {
 	graph += fmt.Sprintf("  start [shape = point];\n")
 	graph += fmt.Sprintf("  start -> %d;\n", dfa.Start)
 
-	for _, state := range dfa.States {
-		if state.IsFinal {
-			graph += fmt.Sprintf("  %d [shape = doublecircle];\n", state.ID)
-		} else {
-			graph += fmt.Sprintf("  %d [shape = circle];\n", state.ID)
-		}
-	}
+	graph += dfa.statesToGraphviz()
 
 	graph += fmt.Sprintf("  %d [color=red, fontcolor=red];\n", currentStateID)
-	graph += fmt.Sprintf("  %d -> error [label=\"%c\"];\n", currentStateID, symbol)
+	label := charclass.Label([]rune{symbol}, nil)
+	graph += fmt.Sprintf("  %d -> error [label=\"%s\"];\n", currentStateID, label)
 	graph += "  error [shape=box, color=red, fontcolor=red];\n"
 
 	graph += fmt.Sprintf("  labelloc=\"t\";\n")
-	graph += fmt.Sprintf("  label=\"Error: No transition for symbol '%c'\";\n", symbol)
+	graph += fmt.Sprintf("  label=\"Error: No transition for symbol '%s'\";\n", label)
 
-	for _, state := range dfa.States {
-		for symbol, nextStateID := range state.Transitions {
-			graph += fmt.Sprintf("  %d -> %d [label=\"%c\"];\n", state.ID, nextStateID, symbol)
-		}
-	}
+	graph += dfa.edgesToGraphviz()
 
 	graph += "}\n"
 	return graph
@@ -1,8 +1,15 @@
 package dfa
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	infixToPostix "github.com/Erlendum/BMSTU_CC/lab_01/internal/infixToPostfix"
 	nfa_pkg "github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
 )
 
@@ -343,3 +350,1093 @@ func checkDFA(t *testing.T, dfa *DFA, expected expectedDFA) {
 		}
 	}
 }
+
+func TestSimulateDFA(t *testing.T) {
+	tests := []struct {
+		postfix  string
+		input    string
+		expected bool
+	}{
+		{"[a-z][a-z0-9_]*.", "x_1", true},
+		{"[a-z][a-z0-9_]*.", "1x", false},
+		{"[a-z][a-z0-9_]*.", "", false},
+		{"a[^a].", "ab", true},
+		{"a[^a].", "aя", true},
+		{"a[^a].", "aa", false},
+		{"[^a-c]", "c", false},
+		{"[^a-c]", "d", true},
+		{"\"[^\"]*.\".", "\"hello\"", true},
+		{"\"[^\"]*.\".", "\"he\"llo\"", false},
+		{`a\+.b.`, "a+b", true},
+		{`a\+.b.`, "aab", false},
+		{`[^\n]*\n.`, "line\n", true},
+		{`[^\n]*\n.`, "li\nne\n", false},
+		{`\u0041\t.`, "A\t", true},
+		{"[0-9]{4}", "2024", true},
+		{"[0-9]{4}", "202", false},
+		{"[0-9]{4}", "20245", false},
+		{"a{2,5}", "a", false},
+		{"a{2,5}", "aa", true},
+		{"a{2,5}", "aaaaa", true},
+		{"a{2,5}", "aaaaaa", false},
+		{"ab.{2,}", "abab", true},
+		{"ab.{2,}", "ababababab", true},
+		{"ab.{2,}", "ab", false},
+		{"a{0,}b.", "b", true},
+		{"a{0,}b.", "aaab", true},
+		{"αβ.*γ.", "αβαβγ", true},
+		{"αβ.*γ.", "αγ", false},
+		{"[а-я]+ё.", "ёжикё", false},
+		{"[а-я]+ё.", "ежикё", true},
+		{`\ε`, "ε", true},
+		{`\ε`, "", false},
+		{"ε", "", true},
+		{"ε", "ε", false},
+		{"aε|b.", "b", true},
+		{"aε|b.", "ab", true},
+		{"aε|b.", "a", false},
+	}
+
+	for _, tt := range tests {
+		minDFA := Build(nfa_pkg.Build(tt.postfix)).Minimize()
+		_, accepted := minDFA.SimulateDFA(tt.input)
+		if accepted != tt.expected {
+			t.Errorf("Postfix: %s, Input: %s, Expected: %v, Actual: %v", tt.postfix, tt.input, tt.expected, accepted)
+		}
+	}
+}
+
+func TestToGraphvizRanges(t *testing.T) {
+	graph := Build(nfa_pkg.Build("[a-z]")).Minimize().ToGraphviz()
+	expected := "  0 -> 1 [label=\"[a-z]\"];\n"
+	if !strings.Contains(graph, expected) {
+		t.Errorf("ожидалось ребро %q, получено:\n%s", expected, graph)
+	}
+}
+
+func TestMinimizeRepeat(t *testing.T) {
+	tests := []struct {
+		postfix        string
+		expectedStates int
+	}{
+		{"[0-9]{4}", 5},
+		{"a{2,5}", 6},
+		{"ab.{2,}", 5},
+		{"a{0,3}", 4},
+	}
+
+	for _, tt := range tests {
+		minDFA := Build(nfa_pkg.Build(tt.postfix)).Minimize()
+		if len(minDFA.States) != tt.expectedStates {
+			t.Errorf("Postfix: %s, ожидаемое количество состояний - %d, получено - %d", tt.postfix, tt.expectedStates, len(minDFA.States))
+		}
+	}
+}
+
+func TestSimulateDFASteps(t *testing.T) {
+	minDFA := Build(nfa_pkg.Build("[а-я]+")).Minimize()
+	steps, accepted := minDFA.SimulateDFA("мир")
+	if !accepted {
+		t.Errorf("ожидалось, что строка мир допускается")
+	}
+
+	// начальный кадр, по кадру на каждый символ и итоговый кадр
+	if len(steps) != 5 {
+		t.Fatalf("ожидалось 5 кадров, получено - %d", len(steps))
+	}
+	if !strings.Contains(steps[3], "Step 3: Symbol 'р'") {
+		t.Errorf("ожидалась подпись третьего шага с символом р, получено:\n%s", steps[3])
+	}
+}
+
+func buildMinDFA(t *testing.T, regex string) *DFA {
+	t.Helper()
+
+	node, err := ast.Parse(regex)
+	if err != nil {
+		t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+	}
+	return Compile(node)
+}
+
+func TestWildcard(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		expected bool
+	}{
+		{".", "x", true},
+		{".", "", false},
+		{".*", "", true},
+		{".*", "что угодно", true},
+		{"a.c", "abc", true},
+		{"a.c", "aac", true},
+		{"a.c", "a.c", true},
+		{"a.c", "ac", false},
+		{`a\.c`, "a.c", true},
+		{`a\.c`, "abc", false},
+		{".*ab", "xxab", true},
+		{".*ab", "xxaba", false},
+	}
+
+	for _, tt := range tests {
+		_, accepted := buildMinDFA(t, tt.regex).SimulateDFA(tt.input)
+		if accepted != tt.expected {
+			t.Errorf("Regex: %s, Input: %s, Expected: %v, Actual: %v", tt.regex, tt.input, tt.expected, accepted)
+		}
+	}
+}
+
+func TestToGraphvizWildcard(t *testing.T) {
+	tests := []struct {
+		regex    string
+		expected string
+	}{
+		{"a.c", "  1 -> 2 [label=\"Σ\"];\n"},
+		{".*", "  0 -> 0 [label=\"Σ\"];\n"},
+		{"a[^a]", "  1 -> 2 [label=\"[^a]\"];\n"},
+	}
+
+	for _, tt := range tests {
+		graph := buildMinDFA(t, tt.regex).ToGraphviz()
+		if !strings.Contains(graph, tt.expected) {
+			t.Errorf("Regex: %s, ожидалось ребро %q, получено:\n%s", tt.regex, tt.expected, graph)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		expected Match
+		found    bool
+	}{
+		{"b+", "aabbbc", Match{2, 5}, true},
+		{"ab|abc", "xabcd", Match{1, 4}, true},
+		{"a*", "bab", Match{0, 0}, true},
+		{"x", "abc", Match{}, false},
+		{"^ab", "abab", Match{0, 2}, true},
+		{"^b", "ab", Match{}, false},
+		{"ab$", "abab", Match{2, 4}, true},
+		{"a+$", "aab", Match{}, false},
+		{"^a*$", "aaa", Match{0, 3}, true},
+		{"^a*$", "aab", Match{}, false},
+		{"мир", "привет, мир", Match{8, 11}, true},
+		{".$", "xyz", Match{2, 3}, true},
+	}
+
+	for _, tt := range tests {
+		match, found := buildMinDFA(t, tt.regex).Search(tt.input)
+		if found != tt.found || match != tt.expected {
+			t.Errorf("Regex: %s, Input: %s, Expected: %v %v, Actual: %v %v", tt.regex, tt.input, tt.expected, tt.found, match, found)
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		expected []Match
+	}{
+		{"[0-9]+", "a1b22c333", []Match{{1, 2}, {3, 5}, {6, 9}}},
+		{"a*", "baab", []Match{{0, 0}, {1, 3}, {4, 4}}},
+		{"^a", "aaa", []Match{{0, 1}}},
+		{"a$", "aaa", []Match{{2, 3}}},
+		{"x", "abc", nil},
+	}
+
+	for _, tt := range tests {
+		var actual []Match
+		for match := range buildMinDFA(t, tt.regex).FindAll(tt.input) {
+			actual = append(actual, match)
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Regex: %s, Input: %s, Expected: %v, Actual: %v", tt.regex, tt.input, tt.expected, actual)
+		}
+	}
+}
+
+// TestSearchMatchesBruteForce сверяет однопроходный поиск с перебором всех
+// подстрок: самое левое начало, а при нем самый длинный конец.
+func TestSearchMatchesBruteForce(t *testing.T) {
+	for _, regex := range []string{"a*b", "ab|abc|c", "(a|b)*abb", "b?a*", "[^a]a+", "ε"} {
+		compiled := buildMinDFA(t, regex)
+
+		for _, word := range wordsUpTo(charclass.Singletons([]rune("abc")...), 5) {
+			runes := []rune(word)
+			expected, expectedFound := Match{}, false
+		search:
+			for start := 0; start <= len(runes); start++ {
+				for end := len(runes); end >= start; end-- {
+					if compiled.Accepts(string(runes[start:end])) {
+						expected, expectedFound = Match{Start: start, End: end}, true
+						break search
+					}
+				}
+			}
+
+			if match, found := compiled.Search(word); found != expectedFound || match != expected {
+				t.Errorf("Regex: %s, Input: %q, Expected: %v %v, Actual: %v %v", regex, word, expected, expectedFound, match, found)
+			}
+		}
+	}
+}
+
+func TestIgnoreCase(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		expected bool
+	}{
+		{"(?i)abc", "AbC", true},
+		{"(?i:abc)d", "ABCd", true},
+		{"(?i:abc)d", "ABCD", false},
+		{"a(?i)b|c", "aB", true},
+		{"a(?i)b|c", "C", true},
+		{"a(?i)b|c", "AB", false},
+		{"((?i)a)b", "AB", false},
+		{"(?i)[a-c]+", "aBcCA", true},
+		{"(?i)[^a]", "A", false},
+		{"(?i)[^a]", "b", true},
+		{"(?i)привет", "ПрИвЕт", true},
+	}
+
+	for _, tt := range tests {
+		_, accepted := buildMinDFA(t, tt.regex).SimulateDFA(tt.input)
+		if accepted != tt.expected {
+			t.Errorf("Regex: %s, Input: %s, Expected: %v, Actual: %v", tt.regex, tt.input, tt.expected, accepted)
+		}
+	}
+}
+
+func TestIgnoreCaseEdges(t *testing.T) {
+	node, err := ast.Parse("(?i:ab)c")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	built := Build(nfa_pkg.FromAST(node))
+	minimized := built.Minimize()
+	if len(minimized.States) != 4 {
+		t.Errorf("ожидалось 4 состояния в минимальном ДКА, получено %d", len(minimized.States))
+	}
+
+	for _, dfa := range []*DFA{built, minimized} {
+		graph := dfa.ToGraphviz()
+		for _, label := range []string{`[label="[Aa]"]`, `[label="[Bb]"]`, `[label="c"]`} {
+			if !strings.Contains(graph, label) {
+				t.Errorf("ожидалось ребро %s, получено:\n%s", label, graph)
+			}
+		}
+	}
+}
+
+// wordsUpTo перебирает все слова длины не больше maxLen над символами алфавита
+// и одним символом вне его (представителем charclass.Other).
+func wordsUpTo(alphabet charclass.Alphabet, maxLen int) []string {
+	symbols := []rune{'§'}
+	for _, rng := range alphabet.Explicit() {
+		symbols = append(symbols, rng.Lo)
+		if rng.Hi != rng.Lo {
+			symbols = append(symbols, rng.Hi)
+		}
+	}
+
+	words := []string{""}
+	level := []string{""}
+	for length := 1; length <= maxLen; length++ {
+		next := []string{}
+		for _, word := range level {
+			for _, symbol := range symbols {
+				next = append(next, word+string(symbol))
+			}
+		}
+		words = append(words, next...)
+		level = next
+	}
+	return words
+}
+
+func TestMinimizeHopcroft(t *testing.T) {
+	tests := []string{
+		"(ab)*c",
+		"(a|b)*abb",
+		"(a|b)*a(a|b)(a|b)",
+		"a*b*|b*a*",
+		"[0-9]{2,4}",
+		"(?i)ab|AB",
+		"a[^b]*b",
+		".*x.*",
+		"(a|ε)(b|ε)",
+		"ε",
+		"(a*|b)+c?",
+	}
+
+	for _, regex := range tests {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+		built := Build(nfa_pkg.FromAST(node))
+
+		brzozowski := built.Minimize()
+		hopcroft := built.MinimizeHopcroft()
+
+		if len(hopcroft.States) != len(brzozowski.States) {
+			t.Errorf("Regex: %s, состояний по Бржозовскому - %d, по Хопкрофту - %d", regex, len(brzozowski.States), len(hopcroft.States))
+		}
+
+		for _, word := range wordsUpTo(built.Alphabet, 4) {
+			expected := brzozowski.Accepts(word)
+			actual := hopcroft.Accepts(word)
+			if actual != expected {
+				t.Errorf("Regex: %s, Input: %q, Expected: %v, Actual: %v", regex, word, expected, actual)
+			}
+		}
+	}
+}
+
+func TestMinimizeHopcroftMergesStates(t *testing.T) {
+	// классический пример: 0 и 3 склеиваются, 1 и 2 тоже, состояние 6 недостижимо
+	dfa := &DFA{
+		Start:    0,
+		Alphabet: charclass.Singletons('0', '1'),
+		States: map[int]*State{
+			0: {ID: 0, Transitions: map[rune]int{'0': 1, '1': 2}},
+			1: {ID: 1, Transitions: map[rune]int{'0': 4, '1': 5}},
+			2: {ID: 2, Transitions: map[rune]int{'0': 5, '1': 4}},
+			3: {ID: 3, Transitions: map[rune]int{'0': 1, '1': 2}},
+			4: {ID: 4, Transitions: map[rune]int{'0': 3, '1': 3}, IsFinal: true},
+			5: {ID: 5, Transitions: map[rune]int{'0': 3, '1': 0}, IsFinal: true},
+			6: {ID: 6, Transitions: map[rune]int{'0': 0}},
+		},
+	}
+
+	minimized := dfa.MinimizeHopcroft()
+	if len(minimized.States) != 3 {
+		t.Errorf("ожидалось 3 состояния, получено %d", len(minimized.States))
+	}
+
+	for _, word := range wordsUpTo(dfa.Alphabet, 6) {
+		expected := dfa.Accepts(word)
+		actual := minimized.Accepts(word)
+		if actual != expected {
+			t.Errorf("Input: %q, Expected: %v, Actual: %v", word, expected, actual)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		regex          string
+		expectedStates int
+	}{
+		{"ab", 4},
+		{"a*", 2},
+		{".*", 1},
+		{"[^a]", 3},
+	}
+
+	for _, tt := range tests {
+		minimized := buildMinDFA(t, tt.regex)
+		complete := minimized.Complete()
+
+		if len(complete.States) != tt.expectedStates {
+			t.Errorf("Regex: %s, ожидаемое количество состояний - %d, получено - %d", tt.regex, tt.expectedStates, len(complete.States))
+		}
+
+		dead := 0
+		for _, state := range complete.States {
+			if state.IsDead {
+				dead++
+			}
+			if len(state.Transitions) != len(complete.Alphabet) {
+				t.Errorf("Regex: %s, у состояния %d переходов %d, а символов в алфавите %d", tt.regex, state.ID, len(state.Transitions), len(complete.Alphabet))
+			}
+		}
+		if dead > 1 {
+			t.Errorf("Regex: %s, ожидалось не больше одного тупикового состояния, получено %d", tt.regex, dead)
+		}
+
+		for _, word := range wordsUpTo(minimized.Alphabet, 3) {
+			if complete.Accepts(word) != minimized.Accepts(word) {
+				t.Errorf("Regex: %s, Input: %q, дополненный автомат допускает другой язык", tt.regex, word)
+			}
+		}
+
+		if len(complete.Minimize().States) != len(minimized.States) {
+			t.Errorf("Regex: %s, минимизация должна убирать тупиковое состояние", tt.regex)
+		}
+	}
+}
+
+func TestCompleteGraphvizAndSimulation(t *testing.T) {
+	complete := buildMinDFA(t, "ab").Complete()
+
+	graph := complete.ToGraphviz()
+	for _, expected := range []string{
+		"  3 [shape = circle, style = dashed, color = gray, fontcolor = gray];\n",
+		"  3 -> 3 [label=\"Σ\"];\n",
+		"  0 -> 3 [label=\"[^a]\"];\n",
+	} {
+		if !strings.Contains(graph, expected) {
+			t.Errorf("ожидалась строка %q, получено:\n%s", expected, graph)
+		}
+	}
+
+	steps, accepted := complete.SimulateDFA("axb")
+	if accepted {
+		t.Errorf("строка axb не должна допускаться")
+	}
+	if len(steps) != 5 {
+		t.Errorf("ожидалось 5 шагов (старт, 3 символа, результат), получено %d", len(steps))
+	}
+	for _, step := range steps {
+		if strings.Contains(step, "error") {
+			t.Errorf("в полном автомате не должно быть шага с ошибкой:\n%s", step)
+		}
+	}
+	if !strings.Contains(steps[len(steps)-1], "  3 [color=red, fontcolor=red];\n") {
+		t.Errorf("последний шаг должен подсвечивать тупиковое состояние:\n%s", steps[len(steps)-1])
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		regex1         string
+		regex2         string
+		equivalent     bool
+		counterexample string
+	}{
+		{"(a|b)*", "(a*b*)*", true, ""},
+		{"a(ba)*", "(ab)*a", true, ""},
+		{"a+", "aa*", true, ""},
+		{"[a-c]", "a|b|c", true, ""},
+		{"(?i)a", "[Aa]", true, ""},
+		{"a{2,3}", "aaa?", true, ""},
+		{"a*", "a+", false, ""},
+		{"ab", "abc?c", false, "ab"},
+		{"(a|b)*", "(a|b)*a(a|b)*", false, ""},
+		{"a|bb", "a|bbb", false, "bb"},
+		{"x.", "x[xy]", false, "xa"},
+		{"[^b]", "a", false, "c"},
+		{"[a-f]|[d-z]", "[a-z]", true, ""},
+		{"[一-龥]+", "[一-龤]+", false, "龥"},
+	}
+
+	for _, tt := range tests {
+		a := buildMinDFA(t, tt.regex1)
+		b := buildMinDFA(t, tt.regex2)
+
+		equivalent, counterexample := Equivalent(a, b)
+		if equivalent != tt.equivalent || counterexample != tt.counterexample {
+			t.Errorf("Regex: %s и %s, Expected: %v %q, Actual: %v %q", tt.regex1, tt.regex2, tt.equivalent, tt.counterexample, equivalent, counterexample)
+		}
+		if !equivalent && a.Accepts(counterexample) == b.Accepts(counterexample) {
+			t.Errorf("Regex: %s и %s, строку %q оба автомата допускают одинаково", tt.regex1, tt.regex2, counterexample)
+		}
+	}
+}
+
+func TestBooleanOperations(t *testing.T) {
+	operations := []struct {
+		name      string
+		operation func(a, b *DFA) *DFA
+		expected  func(inA, inB bool) bool
+	}{
+		{"Intersect", Intersect, func(inA, inB bool) bool { return inA && inB }},
+		{"Union", Union, func(inA, inB bool) bool { return inA || inB }},
+		{"Difference", Difference, func(inA, inB bool) bool { return inA && !inB }},
+	}
+
+	pairs := []struct {
+		regex1 string
+		regex2 string
+	}{
+		{"(a|b)*a", "(a|b)*b(a|b)*"},
+		{"[a-c]+", "x?b*"},
+		{"a[^b]", ".c"},
+		{"(?i)ab", "ab|AB"},
+		{"a*", "ε"},
+	}
+
+	for _, pair := range pairs {
+		a := buildMinDFA(t, pair.regex1)
+		b := buildMinDFA(t, pair.regex2)
+		words := wordsUpTo(unionAlphabet(a, b), 4)
+
+		for _, op := range operations {
+			result := op.operation(a, b)
+			minimized := result.Minimize()
+			hopcroft := result.MinimizeHopcroft()
+
+			for _, word := range words {
+				expected := op.expected(a.Accepts(word), b.Accepts(word))
+				if result.Accepts(word) != expected || minimized.Accepts(word) != expected || hopcroft.Accepts(word) != expected {
+					t.Errorf("%s(%s, %s), Input: %q, Expected: %v", op.name, pair.regex1, pair.regex2, word, expected)
+				}
+			}
+
+			if !strings.HasPrefix(minimized.ToGraphviz(), "digraph DFA {") {
+				t.Errorf("%s(%s, %s): не удалось построить граф", op.name, pair.regex1, pair.regex2)
+			}
+		}
+	}
+}
+
+func TestComplement(t *testing.T) {
+	a := buildMinDFA(t, "a*b")
+
+	all := Complement(a, nil)
+	for _, word := range []string{"", "a", "ba", "c", "aac"} {
+		if !all.Accepts(word) {
+			t.Errorf("дополнение до всех строк должно допускать %q", word)
+		}
+	}
+	for _, word := range []string{"b", "aab"} {
+		if all.Accepts(word) {
+			t.Errorf("дополнение до всех строк не должно допускать %q", word)
+		}
+	}
+
+	overAB := Complement(a, charclass.Singletons('a', 'b'))
+	for word, expected := range map[string]bool{"": true, "ba": true, "ab": false, "c": false, "ac": false} {
+		if overAB.Accepts(word) != expected {
+			t.Errorf("дополнение над {a, b}, Input: %q, Expected: %v", word, expected)
+		}
+	}
+
+	if len(Complement(Complement(a, nil), nil).Minimize().States) != len(a.States) {
+		t.Errorf("двойное дополнение должно давать исходный минимальный автомат")
+	}
+
+	if equivalent, _ := Equivalent(Intersect(a, all), Intersect(all, a)); !equivalent {
+		t.Errorf("пересечение должно быть коммутативным")
+	}
+}
+
+func TestCompileExtended(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		expected bool
+	}{
+		{"[a-z]+&!(if|else)", "foo", true},
+		{"[a-z]+&!(if|else)", "if", false},
+		{"[a-z]+&!(if|else)", "iff", true},
+		{"[a-z]+&!(if|else)", "", false},
+		{"!a", "", true},
+		{"!a", "a", false},
+		{"!a", "b", true},
+		{"!a", "aa", true},
+		{"(!a)b", "ab", false},
+		{"(!a)b", "xb", true},
+		{"(!a)b", "b", true},
+		{"x(a*&(aa)*)y", "xaay", true},
+		{"x(a*&(aa)*)y", "xay", false},
+		{"(!(.*ab.*))c", "bac", true},
+		{"(!(.*ab.*))c", "xabc", false},
+		{"(?i)(a&A)", "A", true},
+		{"a&b", "a", false},
+		{"(a|b)*&!(.*bb.*)", "ababa", true},
+		{"(a|b)*&!(.*bb.*)", "abba", false},
+	}
+
+	for _, tt := range tests {
+		node, err := ast.Parse(tt.regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", tt.regex, err)
+		}
+
+		compiled := Compile(node)
+		if compiled.Accepts(tt.input) != tt.expected {
+			t.Errorf("Compile, Regex: %s, Input: %q, Expected: %v", tt.regex, tt.input, tt.expected)
+		}
+
+		viaNFA := Build(CompileNFA(node)).MinimizeHopcroft()
+		if viaNFA.Accepts(tt.input) != tt.expected {
+			t.Errorf("CompileNFA, Regex: %s, Input: %q, Expected: %v", tt.regex, tt.input, tt.expected)
+		}
+	}
+}
+
+func TestCompileMatchesThompson(t *testing.T) {
+	for _, regex := range []string{"(ab)*c", "a[^b]*b", "(?i)x{2,3}", "ε|a"} {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+
+		if equivalent, counterexample := Equivalent(Compile(node), Build(nfa_pkg.FromAST(node))); !equivalent {
+			t.Errorf("Regex: %s, Compile и FromAST расходятся на %q", regex, counterexample)
+		}
+	}
+}
+
+func TestLanguageAnalysis(t *testing.T) {
+	tests := []struct {
+		regex     string
+		empty     bool
+		finite    bool
+		length    int
+		count     string
+		enumerate []string
+	}{
+		{"ab|c", false, true, 2, "1", []string{"c", "ab"}},
+		{"(a|b)*", false, false, 3, "8", []string{"", "a", "b", "aa", "ab"}},
+		{"a{2,4}", false, true, 3, "1", []string{"aa", "aaa", "aaaa"}},
+		{"[0-9]{3}", false, true, 3, "1000", []string{"000", "001", "002", "003", "004"}},
+		{"a&b", true, true, 1, "0", []string{}},
+		{"(ab)*&!ε", false, false, 4, "1", []string{"ab", "abab", "ababab", "abababab", "ababababab"}},
+		{"b.", false, true, 2, "1112064", []string{"ba", "bb"}},
+		{"[一-龥]x", false, true, 2, "20902", []string{"一x", "丁x", "丂x", "七x", "丄x"}},
+		{"ε", false, true, 0, "1", []string{""}},
+	}
+
+	for _, tt := range tests {
+		node, err := ast.Parse(tt.regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", tt.regex, err)
+		}
+		compiled := Compile(node)
+
+		if compiled.IsEmpty() != tt.empty {
+			t.Errorf("Regex: %s, IsEmpty, Expected: %v", tt.regex, tt.empty)
+		}
+		if compiled.IsFinite() != tt.finite {
+			t.Errorf("Regex: %s, IsFinite, Expected: %v", tt.regex, tt.finite)
+		}
+		if count := compiled.Count(tt.length).String(); count != tt.count {
+			t.Errorf("Regex: %s, Count(%d), Expected: %s, Actual: %s", tt.regex, tt.length, tt.count, count)
+		}
+		if words := compiled.Enumerate(5); !reflect.DeepEqual(words, tt.enumerate) {
+			t.Errorf("Regex: %s, Enumerate(5), Expected: %q, Actual: %q", tt.regex, tt.enumerate, words)
+		}
+	}
+}
+
+func TestToRegex(t *testing.T) {
+	tests := []struct {
+		regex    string
+		expected string
+	}{
+		{"a", "a"},
+		{"ab|c", "c|ab"},
+		{"a|b|c", "[a-c]"},
+		{"(ab)*", "(ab)*"},
+		{"aa*", "a+"},
+		{"a?", "a?"},
+		{"[^x]y", "[^x]y"},
+		{".", "."},
+		{"a&b", "[]"},
+		{`\*\.`, `\*\.`},
+	}
+
+	for _, tt := range tests {
+		actual := buildMinDFA(t, tt.regex).ToRegex().String()
+		if actual != tt.expected {
+			t.Errorf("Regex: %s, Expected: %s, Actual: %s", tt.regex, tt.expected, actual)
+		}
+	}
+}
+
+func TestToRegexEmptyLanguage(t *testing.T) {
+	for _, regex := range []string{"a&b", "[]", "a[]b*"} {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+
+		converted := Compile(node).ToRegex().String()
+		if converted != "[]" {
+			t.Errorf("Regex: %s, ожидалось [], получено %s", regex, converted)
+		}
+
+		// пустой класс есть и в базовом синтаксисе, поэтому выражение проходит через Transform
+		roundTrip := Build(nfa_pkg.Build(infixToPostix.Transform(converted)))
+		if !roundTrip.IsEmpty() {
+			t.Errorf("Regex: %s, язык выражения %s не пуст", regex, converted)
+		}
+	}
+}
+
+func TestFollowpos(t *testing.T) {
+	node, err := ast.Parse("(a|b)*abb")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	expected := "firstpos(корень) = {1, 2, 3}\n" +
+		"позиция  символ  followpos\n" +
+		"1        a       {1, 2, 3}\n" +
+		"2        b       {1, 2, 3}\n" +
+		"3        a       {4}\n" +
+		"4        b       {5}\n" +
+		"5        b       {6}\n" +
+		"6        #       -\n"
+	table, err := Followpos(node)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if actual := table.String(); actual != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+
+	direct, err := BuildDirect(node)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if len(direct.States) != 4 {
+		t.Errorf("ожидалось 4 состояния, получено %d", len(direct.States))
+	}
+	if !reflect.DeepEqual(direct.States[direct.Start].NFAStates, map[int]bool{1: true, 2: true, 3: true}) {
+		t.Errorf("начальное состояние должно быть {1, 2, 3}, получено %v", direct.States[direct.Start].NFAStates)
+	}
+}
+
+func TestFollowposExtended(t *testing.T) {
+	for _, regex := range []string{"a&b", "!a", "(a|!b)*"} {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+
+		if _, err := Followpos(node); !errors.Is(err, ErrExtended) {
+			t.Errorf("Regex: %s, Followpos: ожидалась ErrExtended, получено %v", regex, err)
+		}
+		if _, err := BuildDirect(node); !errors.Is(err, ErrExtended) {
+			t.Errorf("Regex: %s, BuildDirect: ожидалась ErrExtended, получено %v", regex, err)
+		}
+		if _, err := Glushkov(node); !errors.Is(err, ErrExtended) {
+			t.Errorf("Regex: %s, Glushkov: ожидалась ErrExtended, получено %v", regex, err)
+		}
+	}
+}
+
+func TestGlushkov(t *testing.T) {
+	node, err := ast.Parse("(a|b)*abb")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	glushkov, err := Glushkov(node)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	states := glushkov.States()
+	if len(states) != 6 {
+		t.Fatalf("ожидалось 6 состояний (начальное и 5 позиций), получено %d", len(states))
+	}
+	for _, state := range states {
+		if len(state.Transitions[nfa_pkg.EPS]) != 0 {
+			t.Errorf("у состояния %d есть эпсилон-переходы", state.ID)
+		}
+		if state.IsFinal != (state.ID == 5) {
+			t.Errorf("состояние %d: IsFinal=%v", state.ID, state.IsFinal)
+		}
+	}
+
+	targets := []int{}
+	for _, next := range states[0].Transitions['a'] {
+		targets = append(targets, next.ID)
+	}
+	if !reflect.DeepEqual(targets, []int{1, 3}) {
+		t.Errorf("из начального состояния по a ожидались позиции [1 3], получено %v", targets)
+	}
+}
+
+func TestLazyDFA(t *testing.T) {
+	tests := []struct {
+		regex    string
+		alphabet []rune
+	}{
+		{"(a|b)*abb", []rune("ab")},
+		{"(a|b)*a(a|b){3}", []rune("ab")},
+		{"a[^b]*b", []rune("abc")},
+		{"(?i)ab|c", []rune("aAbc")},
+		{"[a-z]+&!(if|else)", []rune("ifx")},
+		{"ε", []rune("a")},
+	}
+
+	for _, tt := range tests {
+		node, err := ast.Parse(tt.regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", tt.regex, err)
+		}
+		expected := Compile(node)
+
+		for _, cacheSize := range []int{1, 2, 1000} {
+			lazy := NewLazy(CompileNFA(node), cacheSize)
+			for _, word := range wordsUpTo(charclass.Singletons(tt.alphabet...), 5) {
+				if actual := lazy.Accepts(word); actual != expected.Accepts(word) {
+					t.Errorf("Regex: %s, cache %d, Input: %q, Expected: %v, Actual: %v", tt.regex, cacheSize, word, !actual, actual)
+				}
+			}
+			// меньше двух состояний кеш не бывает: после сброса в нем текущее и следующее
+			if limit := max(cacheSize, 2); lazy.CachedStates() > limit {
+				t.Errorf("Regex: %s, в кеше %d состояний при пределе %d", tt.regex, lazy.CachedStates(), limit)
+			}
+			checkLazyLinks(t, tt.regex, lazy)
+		}
+	}
+}
+
+// checkLazyLinks проверяет, что после сбросов переходы состояний кеша не ведут
+// в состояния, вытесненные из него.
+func checkLazyLinks(t *testing.T, regex string, lazy *LazyDFA) {
+	cached := make(map[*lazyState]bool)
+	for _, state := range lazy.cache {
+		cached[state] = true
+	}
+	for _, state := range lazy.cache {
+		for symbol, next := range state.next {
+			if next != nil && !cached[next] {
+				t.Errorf("Regex: %s, переход из %v по %q ведет в состояние вне кеша", regex, state.nfaStates, symbol)
+			}
+		}
+	}
+}
+
+func TestLazyDFACache(t *testing.T) {
+	node, err := ast.Parse("(a|b)*a(a|b){20}")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	input := strings.Repeat("ab", 50)
+
+	unbounded := NewLazy(CompileNFA(node), 1<<20)
+	if unbounded.Accepts(input) || !unbounded.Accepts(input+"a"+strings.Repeat("b", 20)) {
+		t.Errorf("неверный результат на длинных строках")
+	}
+	if unbounded.Flushes() != 0 {
+		t.Errorf("кеш без ограничения не должен сбрасываться, сбросов %d", unbounded.Flushes())
+	}
+	visited := unbounded.CachedStates()
+
+	bounded := NewLazy(CompileNFA(node), 8)
+	if bounded.Accepts(input) || !bounded.Accepts(input+"a"+strings.Repeat("b", 20)) {
+		t.Errorf("неверный результат с ограниченным кешем")
+	}
+	if bounded.Flushes() == 0 || bounded.CachedStates() > 8 {
+		t.Errorf("кеш на 8 состояний должен сбрасываться (посещено %d): сбросов %d, в кеше %d", visited, bounded.Flushes(), bounded.CachedStates())
+	}
+	checkLazyLinks(t, "(a|b)*a(a|b){20}", bounded)
+}
+
+func TestBuildWithTrace(t *testing.T) {
+	tests := []string{"(a|b)*abb", "(ab)*c", "a[^b]*b", "ε"}
+
+	for _, regex := range tests {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+
+		nfa := nfa_pkg.FromAST(node)
+		traced, trace := BuildWithTrace(nfa)
+		if equivalent, counterexample := Equivalent(traced, Build(nfa)); !equivalent {
+			t.Errorf("Regex: %s, автоматы расходятся на %q", regex, counterexample)
+		}
+
+		if len(trace.Steps) != len(traced.States)*len(traced.Alphabet) {
+			t.Errorf("Regex: %s, шагов %d вместо %d", regex, len(trace.Steps), len(traced.States)*len(traced.Alphabet))
+		}
+		created := 0
+		for _, step := range trace.Steps {
+			if step.New {
+				created++
+			}
+			if (step.To < 0) != (len(step.Closure) == 0) {
+				t.Errorf("Regex: %s, шаг %+v: переход не согласован с замыканием", regex, step)
+			}
+		}
+		if created != len(traced.States)-1 {
+			t.Errorf("Regex: %s, новых состояний %d вместо %d", regex, created, len(traced.States)-1)
+		}
+
+		if frames := trace.Frames(); len(frames) != len(trace.Steps)+1 {
+			t.Errorf("Regex: %s, кадров %d вместо %d", regex, len(frames), len(trace.Steps)+1)
+		}
+	}
+}
+
+func TestTraceTables(t *testing.T) {
+	node, err := ast.Parse("a|b")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	built, trace := BuildWithTrace(nfa_pkg.FromAST(node))
+
+	start := formatSet(trace.Start)
+	markdown := trace.Markdown()
+	for _, expected := range []string{
+		"| Шаг | Состояние ДКА | Символ | move | ε-замыкание | Результат |",
+		"| 1 | 0 = " + start + " | a |",
+		"новое состояние 1 |",
+		"| → 0 = " + start + " | 1 | 2 |",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("в Markdown нет %q:\n%s", expected, markdown)
+		}
+	}
+
+	latex := trace.LaTeX()
+	for _, expected := range []string{
+		`\begin{tabular}{|l|l|l|l|l|l|}`,
+		`$\varepsilon$-замыкание`,
+		`\{`,
+	} {
+		if !strings.Contains(latex, expected) {
+			t.Errorf("в LaTeX нет %q:\n%s", expected, latex)
+		}
+	}
+
+	graph := built.ToGraphvizWithSets()
+	if !strings.Contains(graph, "  0 [label=\""+start+"\"];") {
+		t.Errorf("состояние 0 не подписано множеством %s:\n%s", start, graph)
+	}
+}
+
+func TestMinimizeStages(t *testing.T) {
+	tests := []struct {
+		regex  string
+		states []int
+	}{
+		{"(a|b)*abb", []int{5, 4, 4, 4}},
+		{"a|b", []int{3, 2, 2, 2}},
+		{"ε", []int{1, 1, 1, 1}},
+		{"[]", []int{0, 1, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		node, err := ast.Parse(tt.regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", tt.regex, err)
+		}
+
+		built := Build(CompileNFA(node))
+		stages := built.MinimizeStages()
+		if len(stages) != 4 {
+			t.Fatalf("Regex: %s, этапов %d вместо 4", tt.regex, len(stages))
+		}
+
+		states := []int{}
+		for i, stage := range stages {
+			if (stage.NFA != nil) != (i%2 == 0) {
+				t.Errorf("Regex: %s, этап %d: обращенный автомат должен быть на нечетных этапах", tt.regex, i+1)
+			}
+			states = append(states, stage.States())
+
+			if graph := stage.ToGraphviz(); !strings.Contains(graph, stage.Caption) || !strings.HasSuffix(graph, "}\n") {
+				t.Errorf("Regex: %s, этап %d: нет подписи в\n%s", tt.regex, i+1, graph)
+			}
+		}
+		if !reflect.DeepEqual(states, tt.states) {
+			t.Errorf("Regex: %s, состояний по этапам %v, ожидалось %v", tt.regex, states, tt.states)
+		}
+
+		if equivalent, counterexample := Equivalent(stages[3].DFA, built); !equivalent {
+			t.Errorf("Regex: %s, минимальный ДКА расходится с исходным на %q", tt.regex, counterexample)
+		}
+	}
+}
+
+func TestMinimizeStagesEmptyLanguage(t *testing.T) {
+	for _, regex := range []string{"[]", "a[]", "a&b"} {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+
+		for i, stage := range Build(CompileNFA(node)).MinimizeStages() {
+			if !stage.Empty() {
+				t.Errorf("Regex: %s, этап %d: язык должен быть пуст", regex, i+1)
+			}
+
+			graph := stage.ToGraphviz()
+			if !strings.Contains(graph, "(язык пуст)") {
+				t.Errorf("Regex: %s, этап %d: нет пометки о пустом языке в\n%s", regex, i+1, graph)
+			}
+			// у обращенного автомата пустого языка начальных состояний нет
+			if stage.NFA != nil && strings.Contains(graph, "start ->") {
+				t.Errorf("Regex: %s, этап %d: лишняя стрелка в начальное состояние\n%s", regex, i+1, graph)
+			}
+		}
+	}
+}
+
+func benchmarkInput(length int) string {
+	var builder strings.Builder
+	// линейный конгруэнтный генератор: строка одна и та же при каждом запуске
+	seed := uint32(1)
+	for i := 0; i < length; i++ {
+		seed = seed*1103515245 + 12345
+		if seed>>16&1 == 0 {
+			builder.WriteByte('a')
+		} else {
+			builder.WriteByte('b')
+		}
+	}
+	return builder.String()
+}
+
+// Жадное построение для (a|b)*a(a|b){n} дает 2^(n+1) состояний и уже при n = 10
+// занимает десятки секунд, поэтому при n = 20 измеряется только ленивый ДКА.
+func BenchmarkLazyDFA(b *testing.B) {
+	input := benchmarkInput(10000)
+
+	for _, n := range []int{10, 20} {
+		node, err := ast.Parse(fmt.Sprintf("(a|b)*a(a|b){%d}", n))
+		if err != nil {
+			b.Fatalf("неожиданная ошибка: %v", err)
+		}
+		compiled := CompileNFA(node)
+
+		for _, cacheSize := range []int{64, 4096, 1 << 20} {
+			b.Run(fmt.Sprintf("n=%d/cache=%d", n, cacheSize), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					NewLazy(compiled, cacheSize).Accepts(input)
+				}
+			})
+		}
+	}
+}
+
+// Поиск a*b в строке из одних a: при запуске автомата с каждой позиции каждый
+// запуск доходит до конца текста, и время растет квадратично.
+func BenchmarkSearch(b *testing.B) {
+	node, err := ast.Parse("a*b")
+	if err != nil {
+		b.Fatalf("неожиданная ошибка: %v", err)
+	}
+	compiled := Compile(node)
+
+	for _, length := range []int{1000, 10000, 100000} {
+		input := strings.Repeat("a", length)
+		b.Run(fmt.Sprintf("len=%d", length), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for range compiled.FindAll(input) {
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEagerDFA(b *testing.B) {
+	input := benchmarkInput(10000)
+
+	for _, n := range []int{4, 6} {
+		node, err := ast.Parse(fmt.Sprintf("(a|b)*a(a|b){%d}", n))
+		if err != nil {
+			b.Fatalf("неожиданная ошибка: %v", err)
+		}
+
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Build(CompileNFA(node)).Minimize().Accepts(input)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package dfa
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+)
+
+// EndMarker - запись концевого маркера, которым дополняется выражение (r)#.
+const EndMarker = "#"
+
+// ErrExtended - у пересечения и дополнения нет позиций, поэтому такие выражения
+// не строятся по followpos (для них есть Compile).
+var ErrExtended = errors.New("пересечение и дополнение не строятся по followpos, используйте Compile")
+
+// Position - лист дополненного выражения. Номера позиций начинаются с 1,
+// последняя позиция - концевой маркер, у нее нет символов.
+type Position struct {
+	Index     int
+	Label     string
+	Symbols   []rune
+	Followpos []int
+}
+
+// FollowposTable - результат разметки дополненного выражения: позиции с
+// followpos и firstpos корня, с которого начинается построение ДКА.
+type FollowposTable struct {
+	Positions []Position
+	Firstpos  []int
+	Alphabet  charclass.Alphabet
+}
+
+// fragment - nullable, firstpos и lastpos поддерева.
+type fragment struct {
+	nullable bool
+	firstpos map[int]bool
+	lastpos  map[int]bool
+}
+
+// positionBuilder нумерует листья при обходе дерева и по ходу заполняет
+// followpos: каждый повтор {n,m} обходит поддерево заново и получает свои позиции.
+type positionBuilder struct {
+	alphabet  charclass.Alphabet
+	positions []Position
+	followpos []map[int]bool
+}
+
+// Followpos размечает выражение (r)# по Ахо-Сети-Ульману: nullable, firstpos,
+// lastpos и followpos. Для выражений с пересечением и дополнением возвращается
+// ErrExtended.
+func Followpos(node *ast.Node) (*FollowposTable, error) {
+	if ast.IsExtended(node) {
+		return nil, ErrExtended
+	}
+
+	b := &positionBuilder{alphabet: ast.Alphabet(node)}
+
+	body := b.build(node)
+	root := b.concatenate(body, b.position(EndMarker, nil))
+
+	table := &FollowposTable{Firstpos: sortedPositions(root.firstpos)}
+	for i, position := range b.positions {
+		position.Followpos = sortedPositions(b.followpos[i])
+		table.Positions = append(table.Positions, position)
+	}
+	table.Alphabet = b.extractAlphabet()
+	return table, nil
+}
+
+func (b *positionBuilder) build(node *ast.Node) fragment {
+	switch node.Type {
+	case ast.NodeLiteral, ast.NodeClass:
+		class := node.EffectiveClass()
+		label := class.String()
+		if node.Type == ast.NodeLiteral && node.Flags&lexer.FlagIgnoreCase == 0 {
+			label = charclass.QuoteRune(node.Symbol)
+		}
+		return b.position(label, class.Symbols(b.alphabet))
+	case ast.NodeAny:
+		return b.position(".", charclass.Class{Negated: true}.Symbols(b.alphabet))
+	case ast.NodeEmpty, ast.NodeBegin, ast.NodeEnd, ast.NodeFlags:
+		return emptyFragment()
+	case ast.NodeAnd, ast.NodeNot:
+		// не встречаются: Followpos заранее проверяет ast.IsExtended
+		panic("пересечение и дополнение не строятся по followpos")
+	case ast.NodeConcat:
+		result := b.build(node.Children[0])
+		for _, child := range node.Children[1:] {
+			result = b.concatenate(result, b.build(child))
+		}
+		return result
+	case ast.NodeAlt:
+		result := b.build(node.Children[0])
+		for _, child := range node.Children[1:] {
+			result = alternate(result, b.build(child))
+		}
+		return result
+	case ast.NodeStar:
+		return b.star(b.build(node.Children[0]))
+	case ast.NodePlus:
+		return b.plus(b.build(node.Children[0]))
+	case ast.NodeOptional:
+		return optional(b.build(node.Children[0]))
+	case ast.NodeRepeat:
+		return b.repeat(node.Children[0], node.Min, node.Max)
+	}
+
+	// NodeGroup влияет только на разбор
+	return b.build(node.Children[0])
+}
+
+func (b *positionBuilder) position(label string, symbols []rune) fragment {
+	index := len(b.positions) + 1
+	b.positions = append(b.positions, Position{Index: index, Label: label, Symbols: symbols})
+	b.followpos = append(b.followpos, make(map[int]bool))
+
+	return fragment{
+		firstpos: map[int]bool{index: true},
+		lastpos:  map[int]bool{index: true},
+	}
+}
+
+func emptyFragment() fragment {
+	return fragment{nullable: true, firstpos: map[int]bool{}, lastpos: map[int]bool{}}
+}
+
+// follow добавляет to в followpos каждой позиции из from.
+func (b *positionBuilder) follow(from, to map[int]bool) {
+	for i := range from {
+		for j := range to {
+			b.followpos[i-1][j] = true
+		}
+	}
+}
+
+func (b *positionBuilder) concatenate(left, right fragment) fragment {
+	b.follow(left.lastpos, right.firstpos)
+
+	result := fragment{
+		nullable: left.nullable && right.nullable,
+		firstpos: left.firstpos,
+		lastpos:  right.lastpos,
+	}
+	if left.nullable {
+		result.firstpos = union(left.firstpos, right.firstpos)
+	}
+	if right.nullable {
+		result.lastpos = union(left.lastpos, right.lastpos)
+	}
+	return result
+}
+
+func alternate(left, right fragment) fragment {
+	return fragment{
+		nullable: left.nullable || right.nullable,
+		firstpos: union(left.firstpos, right.firstpos),
+		lastpos:  union(left.lastpos, right.lastpos),
+	}
+}
+
+func (b *positionBuilder) star(child fragment) fragment {
+	child = b.plus(child)
+	child.nullable = true
+	return child
+}
+
+func (b *positionBuilder) plus(child fragment) fragment {
+	b.follow(child.lastpos, child.firstpos)
+	return child
+}
+
+func optional(child fragment) fragment {
+	child.nullable = true
+	return child
+}
+
+// repeat раскрывает {min,max} так же, как построение по Томпсону: min
+// обязательных копий и max-min необязательных, а при max = lexer.Unbounded
+// последняя обязательная копия замыкается плюсом (или все звездой при min = 0).
+func (b *positionBuilder) repeat(child *ast.Node, min, max int) fragment {
+	count := max
+	if max == lexer.Unbounded {
+		count = min
+		if count == 0 {
+			count = 1
+		}
+	}
+
+	result := emptyFragment()
+	for i := 0; i < count; i++ {
+		copied := b.build(child)
+		switch {
+		case max == lexer.Unbounded && min == 0:
+			copied = b.star(copied)
+		case max == lexer.Unbounded && i == min-1:
+			copied = b.plus(copied)
+		case i >= min:
+			copied = optional(copied)
+		}
+		result = b.concatenate(result, copied)
+	}
+	return result
+}
+
+// extractAlphabet - алфавит выражения вместе с Other, если он есть у какой-нибудь
+// позиции, как у nfa.NFA.ExtractAlphabet.
+func (b *positionBuilder) extractAlphabet() charclass.Alphabet {
+	for _, position := range b.positions {
+		if containsSymbol(position.Symbols, charclass.Other) {
+			return b.alphabet.WithOther()
+		}
+	}
+	return b.alphabet
+}
+
+func union(a, b map[int]bool) map[int]bool {
+	result := make(map[int]bool, len(a)+len(b))
+	for i := range a {
+		result[i] = true
+	}
+	for i := range b {
+		result[i] = true
+	}
+	return result
+}
+
+func sortedPositions(set map[int]bool) []int {
+	positions := make([]int, 0, len(set))
+	for i := range set {
+		positions = append(positions, i)
+	}
+	sort.Ints(positions)
+	return positions
+}
+
+// String выводит таблицу followpos в том виде, как ее строят вручную: позиция,
+// символ позиции и followpos, а перед таблицей - firstpos корня.
+func (t *FollowposTable) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "firstpos(корень) = %s\n", formatPositions(t.Firstpos))
+
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "позиция\tсимвол\tfollowpos")
+	for _, position := range t.Positions {
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", position.Index, position.Label, formatPositions(position.Followpos))
+	}
+	writer.Flush()
+
+	return builder.String()
+}
+
+func formatPositions(positions []int) string {
+	if len(positions) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, len(positions))
+	for i, position := range positions {
+		parts[i] = fmt.Sprint(position)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// BuildDirect строит ДКА прямо по выражению, без НКА: состояние - множество
+// позиций, начальное - firstpos корня, переход по символу a - объединение
+// followpos позиций с символом a, заключительные содержат концевой маркер.
+// NFAStates состояний хранят номера позиций. Для выражений с пересечением и
+// дополнением возвращается ErrExtended.
+func BuildDirect(node *ast.Node) (*DFA, error) {
+	table, err := Followpos(node)
+	if err != nil {
+		return nil, err
+	}
+	endMarker := len(table.Positions)
+
+	dfa := &DFA{
+		States:   make(map[int]*State),
+		Alphabet: table.Alphabet,
+	}
+	dfa.AnchorStart, dfa.AnchorEnd = ast.Anchors(node)
+
+	newState := func(positions map[int]bool) *State {
+		state := NewState(len(dfa.States), positions, positions[endMarker])
+		dfa.States[state.ID] = state
+		return state
+	}
+
+	start := make(map[int]bool)
+	for _, position := range table.Firstpos {
+		start[position] = true
+	}
+	dfa.Start = newState(start).ID
+
+	for queue := []int{dfa.Start}; len(queue) > 0; queue = queue[1:] {
+		current := dfa.States[queue[0]]
+
+		for _, symbol := range dfa.Alphabet.Symbols() {
+			next := make(map[int]bool)
+			for index := range current.NFAStates {
+				position := table.Positions[index-1]
+				if containsSymbol(position.Symbols, symbol) {
+					for _, follow := range position.Followpos {
+						next[follow] = true
+					}
+				}
+			}
+			if len(next) == 0 {
+				continue
+			}
+
+			var nextState *State
+			for id := 0; id < len(dfa.States); id++ {
+				if statesEqual(dfa.States[id].NFAStates, next) {
+					nextState = dfa.States[id]
+					break
+				}
+			}
+			if nextState == nil {
+				nextState = newState(next)
+				queue = append(queue, nextState.ID)
+			}
+
+			current.Transitions[symbol] = nextState.ID
+		}
+	}
+
+	return dfa, nil
+}
@@ -0,0 +1,51 @@
+package dfa
+
+import (
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	nfa_pkg "github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+)
+
+// Glushkov строит позиционный НКА Глушкова по той же разметке, что и BuildDirect:
+// состояние 0 - начальное, состояние i - позиция i. Из 0 переходы ведут в
+// позиции firstpos, из позиции p - в позиции followpos(p), переход в позицию
+// подписан ее символами. Заключительные - позиции lastpos и 0, если выражение
+// допускает пустую строку (то есть после них может идти концевой маркер).
+// Эпсилон-переходов нет, состояний на одно больше, чем позиций. Для выражений
+// с пересечением и дополнением возвращается ErrExtended.
+func Glushkov(node *ast.Node) (*nfa_pkg.NFA, error) {
+	table, err := Followpos(node)
+	if err != nil {
+		return nil, err
+	}
+	endMarker := len(table.Positions)
+
+	states := make([]*nfa_pkg.State, endMarker)
+	for i := range states {
+		states[i] = nfa_pkg.NewState(i)
+	}
+
+	connect := func(from *nfa_pkg.State, positions []int) {
+		for _, index := range positions {
+			if index == endMarker {
+				from.IsFinal = true
+				continue
+			}
+			for _, symbol := range table.Positions[index-1].Symbols {
+				from.Transitions[symbol] = append(from.Transitions[symbol], states[index])
+			}
+		}
+	}
+
+	connect(states[0], table.Firstpos)
+	for _, position := range table.Positions[:endMarker-1] {
+		connect(states[position.Index], position.Followpos)
+	}
+
+	result := &nfa_pkg.NFA{
+		Start:       states[0],
+		StartStates: []*nfa_pkg.State{states[0]},
+		Alphabet:    table.Alphabet,
+	}
+	result.AnchorStart, result.AnchorEnd = ast.Anchors(node)
+	return result, nil
+}
@@ -0,0 +1,241 @@
+package dfa
+
+import "sort"
+
+// MinimizeHopcroft минимизирует автомат алгоритмом Хопкрофта (разбиение на
+// классы эквивалентности за O(n log n)). Результат совпадает с Minimize с
+// точностью до номеров состояний: недостижимые состояния и состояния, из
+// которых нельзя попасть в заключительное, отбрасываются. NFAStates состояния
+// результата - объединение NFAStates склеенных в него состояний.
+func (dfa *DFA) MinimizeHopcroft() *DFA {
+	states := dfa.reachableStates()
+	alphabet := dfa.Alphabet.Symbols()
+
+	// состояния нумеруются подряд, последнее - добавленное тупиковое, в которое
+	// ведут все отсутствующие переходы
+	index := make(map[int]int, len(states))
+	for i, id := range states {
+		index[id] = i
+	}
+	dead := len(states)
+	count := dead + 1
+
+	transitions := make([][]int, count)
+	for i := range transitions {
+		transitions[i] = make([]int, len(alphabet))
+		for j := range alphabet {
+			transitions[i][j] = dead
+		}
+	}
+	for i, id := range states {
+		for j, symbol := range alphabet {
+			if nextStateID, ok := dfa.States[id].Transitions[symbol]; ok {
+				transitions[i][j] = index[nextStateID]
+			}
+		}
+	}
+
+	predecessors := make([][][]int, len(alphabet))
+	for j := range alphabet {
+		predecessors[j] = make([][]int, count)
+		for i := 0; i < count; i++ {
+			next := transitions[i][j]
+			predecessors[j][next] = append(predecessors[j][next], i)
+		}
+	}
+
+	blocks, blockOf := initialPartition(dfa, states, count)
+	blocks, blockOf = refine(blocks, blockOf, predecessors, len(alphabet))
+
+	return dfa.fromPartition(states, blocks, blockOf, transitions, dead)
+}
+
+func (dfa *DFA) reachableStates() []int {
+	states := []int{}
+	for id := range dfa.reachable() {
+		states = append(states, id)
+	}
+
+	sort.Ints(states)
+	return states
+}
+
+// initialPartition делит состояния на заключительные и остальные (вместе с
+// тупиковым), пустой блок не создается.
+func initialPartition(dfa *DFA, states []int, count int) ([][]int, []int) {
+	final, other := []int{}, []int{}
+	for i := 0; i < count; i++ {
+		if i < len(states) && dfa.States[states[i]].IsFinal {
+			final = append(final, i)
+		} else {
+			other = append(other, i)
+		}
+	}
+
+	blocks := [][]int{}
+	blockOf := make([]int, count)
+	for _, block := range [][]int{final, other} {
+		if len(block) == 0 {
+			continue
+		}
+		for _, state := range block {
+			blockOf[state] = len(blocks)
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, blockOf
+}
+
+type splitter struct {
+	block  int
+	symbol int
+}
+
+// refine дробит блоки, пока каждый блок не станет согласован со всеми
+// остальными по всем символам. В очередь на каждом разбиении попадает меньшая
+// половина, за счет чего и получается O(n log n).
+func refine(blocks [][]int, blockOf []int, predecessors [][][]int, symbols int) ([][]int, []int) {
+	queue := []splitter{}
+	inQueue := make(map[splitter]bool)
+	push := func(s splitter) {
+		if !inQueue[s] {
+			inQueue[s] = true
+			queue = append(queue, s)
+		}
+	}
+
+	smallest := 0
+	for i := range blocks {
+		if len(blocks[i]) < len(blocks[smallest]) {
+			smallest = i
+		}
+	}
+	for symbol := 0; symbol < symbols; symbol++ {
+		push(splitter{block: smallest, symbol: symbol})
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		inQueue[current] = false
+
+		// состояния, из которых по символу попадаем в блок-разделитель
+		marked := make(map[int][]int)
+		for _, state := range blocks[current.block] {
+			for _, prev := range predecessors[current.symbol][state] {
+				marked[blockOf[prev]] = append(marked[blockOf[prev]], prev)
+			}
+		}
+
+		touched := make([]int, 0, len(marked))
+		for block := range marked {
+			touched = append(touched, block)
+		}
+		sort.Ints(touched)
+
+		for _, block := range touched {
+			inside := uniqueStates(marked[block])
+			if len(inside) == len(blocks[block]) {
+				continue
+			}
+
+			isInside := make(map[int]bool, len(inside))
+			for _, state := range inside {
+				isInside[state] = true
+			}
+			outside := []int{}
+			for _, state := range blocks[block] {
+				if !isInside[state] {
+					outside = append(outside, state)
+				}
+			}
+
+			blocks[block] = inside
+			newBlock := len(blocks)
+			blocks = append(blocks, outside)
+			for _, state := range outside {
+				blockOf[state] = newBlock
+			}
+
+			for symbol := 0; symbol < symbols; symbol++ {
+				if inQueue[splitter{block: block, symbol: symbol}] || len(outside) <= len(inside) {
+					push(splitter{block: newBlock, symbol: symbol})
+				} else {
+					push(splitter{block: block, symbol: symbol})
+				}
+			}
+		}
+	}
+
+	return blocks, blockOf
+}
+
+func uniqueStates(states []int) []int {
+	seen := make(map[int]bool, len(states))
+	result := []int{}
+	for _, state := range states {
+		if !seen[state] {
+			seen[state] = true
+			result = append(result, state)
+		}
+	}
+	return result
+}
+
+// fromPartition собирает автомат из блоков: номера выдаются обходом в ширину
+// от стартового блока, блок тупикового состояния отбрасывается.
+func (dfa *DFA) fromPartition(states []int, blocks [][]int, blockOf []int, transitions [][]int, dead int) *DFA {
+	minimized := &DFA{
+		States:      make(map[int]*State),
+		Alphabet:    dfa.Alphabet,
+		AnchorStart: dfa.AnchorStart,
+		AnchorEnd:   dfa.AnchorEnd,
+	}
+
+	deadBlock := blockOf[dead]
+	startBlock := blockOf[0]
+	for i, id := range states {
+		if id == dfa.Start {
+			startBlock = blockOf[i]
+		}
+	}
+
+	newID := map[int]int{startBlock: 0}
+	queue := []int{startBlock}
+	for i := 0; i < len(queue); i++ {
+		block := queue[i]
+		representative := blocks[block][0]
+
+		nfaStates := make(map[int]bool)
+		for _, state := range blocks[block] {
+			if state == dead {
+				continue
+			}
+			for nfaStateID := range dfa.States[states[state]].NFAStates {
+				nfaStates[nfaStateID] = true
+			}
+		}
+
+		state := NewState(newID[block], nfaStates, representative != dead && dfa.States[states[representative]].IsFinal)
+		minimized.States[state.ID] = state
+
+		if block == deadBlock {
+			continue
+		}
+
+		for j, symbol := range dfa.Alphabet.Symbols() {
+			nextBlock := blockOf[transitions[representative][j]]
+			if nextBlock == deadBlock {
+				continue
+			}
+			if _, ok := newID[nextBlock]; !ok {
+				newID[nextBlock] = len(queue)
+				queue = append(queue, nextBlock)
+			}
+			state.Transitions[symbol] = newID[nextBlock]
+		}
+	}
+
+	return minimized
+}
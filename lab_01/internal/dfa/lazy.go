@@ -0,0 +1,184 @@
+package dfa
+
+import (
+	"strconv"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	nfa_pkg "github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+)
+
+// LazyDFA - детерминизация по требованию, как в RE2: НКА моделируется
+// множествами состояний, а каждое встреченное множество запоминается как
+// состояние ДКА вместе с уже вычисленными переходами. В кеше хранится не больше
+// cacheSize состояний; когда он заполнен, кеш сбрасывается целиком, а текущее
+// состояние моделирования добавляется в новый кеш заново.
+type LazyDFA struct {
+	alphabet  charclass.Alphabet
+	states    []*nfa_pkg.State // состояния НКА по номерам
+	index     map[*nfa_pkg.State]int
+	start     []int
+	cacheSize int
+
+	cache   map[string]*lazyState
+	flushes int
+}
+
+// lazyState - состояние ДКА: отсортированные номера состояний НКА и переходы,
+// вычисленные к этому моменту. Отсутствующий в next символ еще не вычислялся,
+// nil означает, что переход ведет в пустое множество. Переходы ведут только в
+// состояния того же кеша.
+type lazyState struct {
+	nfaStates []int
+	isFinal   bool
+	next      map[rune]*lazyState
+}
+
+// NewLazy готовит ленивый ДКА для nfa. cacheSize - наибольшее число состояний в
+// кеше, не меньше 2: после сброса в кеше должны поместиться текущее состояние и
+// следующее.
+func NewLazy(nfa *nfa_pkg.NFA, cacheSize int) *LazyDFA {
+	if cacheSize < 2 {
+		cacheSize = 2
+	}
+
+	lazy := &LazyDFA{
+		alphabet:  nfa.ExtractAlphabet(),
+		states:    nfa.States(),
+		index:     make(map[*nfa_pkg.State]int),
+		cacheSize: cacheSize,
+		cache:     make(map[string]*lazyState),
+	}
+	for i, state := range lazy.states {
+		lazy.index[state] = i
+	}
+
+	starts := make([]int, 0, len(nfa.StartStates))
+	for _, state := range nfa.StartStates {
+		starts = append(starts, lazy.index[state])
+	}
+	lazy.start = lazy.closure(starts)
+
+	return lazy
+}
+
+// closure - эпсилон-замыкание множества состояний НКА в виде отсортированных номеров.
+func (lazy *LazyDFA) closure(states []int) []int {
+	visited := make([]bool, len(lazy.states))
+	stack := []int{}
+	for _, state := range states {
+		if !visited[state] {
+			visited[state] = true
+			stack = append(stack, state)
+		}
+	}
+
+	for len(stack) > 0 {
+		state := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, nextState := range lazy.states[state].Transitions[nfa_pkg.EPS] {
+			if next := lazy.index[nextState]; !visited[next] {
+				visited[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+
+	return setOf(visited)
+}
+
+func setOf(members []bool) []int {
+	set := []int{}
+	for state, ok := range members {
+		if ok {
+			set = append(set, state)
+		}
+	}
+	return set
+}
+
+func setKey(states []int) string {
+	key := make([]byte, 0, 4*len(states))
+	for _, state := range states {
+		key = strconv.AppendInt(key, int64(state), 10)
+		key = append(key, ',')
+	}
+	return string(key)
+}
+
+// state возвращает состояние ДКА для множества из кеша или добавляет его. Если
+// кеш заполнен, он сбрасывается, и состояние keep (текущее состояние
+// моделирования, может быть nil) добавляется в новый кеш заново: вторым
+// значением возвращается его новая копия, которую и нужно использовать дальше.
+func (lazy *LazyDFA) state(nfaStates []int, keep *lazyState) (*lazyState, *lazyState) {
+	if cached, ok := lazy.cache[setKey(nfaStates)]; ok {
+		return cached, keep
+	}
+
+	if len(lazy.cache) >= lazy.cacheSize {
+		lazy.cache = make(map[string]*lazyState)
+		lazy.flushes++
+		if keep != nil {
+			keep = lazy.add(keep.nfaStates)
+		}
+	}
+	return lazy.add(nfaStates), keep
+}
+
+func (lazy *LazyDFA) add(nfaStates []int) *lazyState {
+	state := &lazyState{nfaStates: nfaStates, next: make(map[rune]*lazyState)}
+	for _, id := range nfaStates {
+		if lazy.states[id].IsFinal {
+			state.isFinal = true
+			break
+		}
+	}
+	lazy.cache[setKey(nfaStates)] = state
+	return state
+}
+
+// step возвращает переход из state по символу алфавита, вычисляя его при первом
+// обращении, и состояние, из которого моделирование продолжается: после сброса
+// кеша это новая копия state.
+func (lazy *LazyDFA) step(state *lazyState, symbol rune) (*lazyState, *lazyState) {
+	if next, ok := state.next[symbol]; ok {
+		return next, state
+	}
+
+	targets := []int{}
+	for _, id := range state.nfaStates {
+		for _, nextState := range lazy.states[id].Transitions[symbol] {
+			targets = append(targets, lazy.index[nextState])
+		}
+	}
+
+	var next *lazyState
+	if len(targets) > 0 {
+		next, state = lazy.state(lazy.closure(targets), state)
+	}
+	state.next[symbol] = next
+	return next, state
+}
+
+// Accepts допускает строку по тем же правилам, что SimulateDFA: символы вне
+// алфавита идут по charclass.Other, без перехода строка отвергается.
+func (lazy *LazyDFA) Accepts(input string) bool {
+	current, _ := lazy.state(lazy.start, nil)
+	for _, r := range input {
+		current, _ = lazy.step(current, lazy.alphabet.Find(r))
+		if current == nil {
+			return false
+		}
+	}
+	return current.isFinal
+}
+
+// CachedStates - число состояний ДКА в кеше сейчас.
+func (lazy *LazyDFA) CachedStates() int {
+	return len(lazy.cache)
+}
+
+// Flushes - сколько раз кеш сбрасывался из-за переполнения.
+func (lazy *LazyDFA) Flushes() int {
+	return lazy.flushes
+}
@@ -0,0 +1,161 @@
+package dfa
+
+import (
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+)
+
+// deadState - номер отсутствующего состояния в произведении автоматов: в него
+// ведут неопределенные переходы, и из него нет выхода.
+const deadState = -1
+
+type statePair struct {
+	a int
+	b int
+}
+
+// unionAlphabet объединяет алфавиты двух автоматов, измельчая их интервалы
+// (charclass.Refine). Other входит в результат всегда: он обозначает символы,
+// которых нет ни в одном из выражений.
+func unionAlphabet(a, b *DFA) charclass.Alphabet {
+	return charclass.Refine(a.Alphabet, b.Alphabet).WithOther()
+}
+
+// step делает переход по символу объединенного алфавита: он лежит внутри
+// одного интервала алфавита автомата или вне всех (тогда неотличим от Other).
+func (dfa *DFA) step(stateID int, symbol rune) int {
+	if stateID == deadState {
+		return deadState
+	}
+
+	if nextStateID, ok := dfa.States[stateID].Transitions[dfa.Alphabet.Find(symbol)]; ok {
+		return nextStateID
+	}
+	return deadState
+}
+
+func (dfa *DFA) isFinal(stateID int) bool {
+	return stateID != deadState && dfa.States[stateID].IsFinal
+}
+
+// Equivalent проверяет, задают ли автоматы один и тот же язык, обходом в
+// ширину их произведения. Если нет, возвращает кратчайшую строку, которую
+// допускает ровно один из них (Other записывается символом вне обоих алфавитов).
+func Equivalent(a, b *DFA) (bool, string) {
+	alphabet := unionAlphabet(a, b)
+	other := charclass.Representative(alphabet)
+
+	type visit struct {
+		parent statePair
+		symbol rune
+	}
+
+	start := statePair{a: a.Start, b: b.Start}
+	visited := map[statePair]visit{start: {}}
+	queue := []statePair{start}
+
+	for i := 0; i < len(queue); i++ {
+		current := queue[i]
+
+		if a.isFinal(current.a) != b.isFinal(current.b) {
+			word := []rune{}
+			for pair := current; pair != start; pair = visited[pair].parent {
+				symbol := visited[pair].symbol
+				if symbol == charclass.Other {
+					symbol = other
+				}
+				word = append(word, symbol)
+			}
+			for l, r := 0, len(word)-1; l < r; l, r = l+1, r-1 {
+				word[l], word[r] = word[r], word[l]
+			}
+			return false, string(word)
+		}
+
+		for _, symbol := range alphabet.Symbols() {
+			next := statePair{a: a.step(current.a, symbol), b: b.step(current.b, symbol)}
+			if next.a == deadState && next.b == deadState {
+				continue
+			}
+			if _, ok := visited[next]; !ok {
+				visited[next] = visit{parent: current, symbol: symbol}
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// Intersect строит автомат для пересечения языков. Как и остальные операции над
+// языками, работает с языком целых строк: якоря операндов не переносятся.
+func Intersect(a, b *DFA) *DFA {
+	return product(a, b, unionAlphabet(a, b), func(inA, inB bool) bool {
+		return inA && inB
+	})
+}
+
+// Union строит автомат для объединения языков.
+func Union(a, b *DFA) *DFA {
+	return product(a, b, unionAlphabet(a, b), func(inA, inB bool) bool {
+		return inA || inB
+	})
+}
+
+// Difference строит автомат для строк, которые допускает a, но не допускает b.
+func Difference(a, b *DFA) *DFA {
+	return product(a, b, unionAlphabet(a, b), func(inA, inB bool) bool {
+		return inA && !inB
+	})
+}
+
+// Complement строит автомат для дополнения языка до всех строк над alphabet.
+// Если в alphabet есть charclass.Other, в него входят и все символы, которых нет
+// в выражении; при пустом alphabet берется алфавит автомата вместе с Other.
+func Complement(a *DFA, alphabet charclass.Alphabet) *DFA {
+	if len(alphabet) == 0 {
+		alphabet = unionAlphabet(a, a)
+	}
+	return product(a, a, alphabet, func(inA, _ bool) bool {
+		return !inA
+	})
+}
+
+// product обходит в ширину пары состояний двух автоматов, считая отсутствующие
+// переходы переходами в тупик. Пара заключительна, если accept от
+// заключительности ее компонент истинен. Пару из двух тупиков имеет смысл
+// хранить, только если accept(false, false) истинен (как у дополнения).
+func product(a, b *DFA, alphabet charclass.Alphabet, accept func(inA, inB bool) bool) *DFA {
+	result := &DFA{
+		Start:    0,
+		States:   make(map[int]*State),
+		Alphabet: alphabet,
+	}
+	keepDead := accept(false, false)
+
+	start := statePair{a: a.Start, b: b.Start}
+	ids := map[statePair]int{start: 0}
+	queue := []statePair{start}
+
+	for i := 0; i < len(queue); i++ {
+		current := queue[i]
+		state := NewState(i, map[int]bool{}, accept(a.isFinal(current.a), b.isFinal(current.b)))
+		result.States[i] = state
+
+		for _, symbol := range alphabet.Symbols() {
+			next := statePair{a: a.step(current.a, symbol), b: b.step(current.b, symbol)}
+			if next.a == deadState && next.b == deadState && !keepDead {
+				continue
+			}
+
+			nextID, ok := ids[next]
+			if !ok {
+				nextID = len(queue)
+				ids[next] = nextID
+				queue = append(queue, next)
+			}
+			state.Transitions[symbol] = nextID
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,102 @@
+package dfa
+
+import "iter"
+
+// Match - найденное вхождение: символы текста с Start по End-1. Позиции считаются
+// в символах (рунах), как и позиции в синтаксических ошибках.
+type Match struct {
+	Start int
+	End   int
+}
+
+// Search ищет самое левое, а среди них самое длинное вхождение выражения в текст
+// за один проход по тексту.
+func (dfa *DFA) Search(input string) (Match, bool) {
+	return dfa.searchFrom([]rune(input), 0)
+}
+
+// FindAll перебирает непересекающиеся вхождения слева направо, как Search. Пустое
+// вхождение сразу после предыдущего пропускается.
+func (dfa *DFA) FindAll(input string) iter.Seq[Match] {
+	return func(yield func(Match) bool) {
+		runes := []rune(input)
+		prevEnd := -1
+
+		for pos := 0; pos <= len(runes); {
+			match, ok := dfa.searchFrom(runes, pos)
+			if !ok {
+				return
+			}
+
+			if match.Start == match.End && match.Start == prevEnd {
+				pos = match.Start + 1
+				continue
+			}
+			if !yield(match) {
+				return
+			}
+
+			prevEnd = match.End
+			pos = match.End
+			if match.Start == match.End {
+				pos++
+			}
+		}
+	}
+}
+
+// searchFrom ищет вхождение, начинающееся не раньше from, за один проход по
+// тексту. Для каждого состояния ДКА хранится самое раннее начало, с которого в
+// него можно прийти: продолжения у одного состояния одинаковы, поэтому более
+// позднее начало ничего не добавляет и состояний на позиции не больше, чем в
+// ДКА. Пока вхождение не найдено, на каждой позиции добавляется новое начало;
+// после этого остаются только начала не правее найденного.
+func (dfa *DFA) searchFrom(runes []rune, from int) (Match, bool) {
+	starts, next := make(map[int]int), make(map[int]int)
+	match, found := Match{}, false
+
+	for pos := from; ; pos++ {
+		if !found && (!dfa.AnchorStart || pos == 0) {
+			if _, ok := starts[dfa.Start]; !ok {
+				starts[dfa.Start] = pos
+			}
+		}
+
+		if !dfa.AnchorEnd || pos == len(runes) {
+			for stateID, start := range starts {
+				if !dfa.States[stateID].IsFinal {
+					continue
+				}
+				if !found || start < match.Start || start == match.Start && pos > match.End {
+					match, found = Match{Start: start, End: pos}, true
+				}
+			}
+		}
+		if found {
+			for stateID, start := range starts {
+				if start > match.Start {
+					delete(starts, stateID)
+				}
+			}
+		}
+
+		if pos == len(runes) || len(starts) == 0 && (found || dfa.AnchorStart) {
+			break
+		}
+
+		clear(next)
+		symbol := dfa.alphabetSymbol(runes[pos])
+		for stateID, start := range starts {
+			nextStateID, exists := dfa.States[stateID].Transitions[symbol]
+			if !exists {
+				continue
+			}
+			if earliest, ok := next[nextStateID]; !ok || start < earliest {
+				next[nextStateID] = start
+			}
+		}
+		starts, next = next, starts
+	}
+
+	return match, found
+}
@@ -0,0 +1,295 @@
+package dfa
+
+import (
+	"sort"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+)
+
+// ToRegex строит по автомату регулярное выражение методом исключения состояний.
+// Выражение записано в инфиксном синтаксисе Parse и Transform (node.String()) и
+// по ходу упрощается: пустые строки и пустые множества сокращаются, одинаковые
+// ветки склеиваются, символы одной альтернативы собираются в класс, xx* пишется
+// как x+. Для пустого языка возвращается пустой класс [].
+func (dfa *DFA) ToRegex() *ast.Node {
+	useful := dfa.usefulStates()
+	if !useful[dfa.Start] {
+		return &ast.Node{Type: ast.NodeClass}
+	}
+
+	// обобщенный автомат: ребра подписаны выражениями, nil - нет ребра
+	start, end := -1, -2
+	edges := map[int]map[int]*ast.Node{start: {dfa.Start: emptyNode()}}
+	addEdge := func(from, to int, label *ast.Node) {
+		if edges[from] == nil {
+			edges[from] = make(map[int]*ast.Node)
+		}
+		edges[from][to] = alternation(edges[from][to], label)
+	}
+
+	states := []int{}
+	for id := range useful {
+		states = append(states, id)
+	}
+	sort.Ints(states)
+
+	for _, id := range states {
+		state := dfa.States[id]
+		symbols := make(map[int][]rune)
+		for symbol, nextStateID := range state.Transitions {
+			if useful[nextStateID] {
+				symbols[nextStateID] = append(symbols[nextStateID], symbol)
+			}
+		}
+		for nextStateID, group := range symbols {
+			addEdge(id, nextStateID, dfa.symbolsNode(group))
+		}
+		if state.IsFinal {
+			addEdge(id, end, emptyNode())
+		}
+	}
+
+	for remaining := states; len(remaining) > 0; {
+		// первым исключается состояние с наименьшим числом пар вход-выход,
+		// так выражение получается короче
+		best := 0
+		for i := range remaining {
+			if eliminationCost(edges, remaining[i]) < eliminationCost(edges, remaining[best]) {
+				best = i
+			}
+		}
+		eliminated := remaining[best]
+		remaining = append(append([]int{}, remaining[:best]...), remaining[best+1:]...)
+
+		loop := star(edges[eliminated][eliminated])
+		for _, from := range sortedKeys(edges) {
+			in, ok := edges[from][eliminated]
+			if !ok || from == eliminated {
+				continue
+			}
+			for _, to := range sortedKeys(edges[eliminated]) {
+				if to == eliminated {
+					continue
+				}
+				addEdge(from, to, concatenation(in, loop, edges[eliminated][to]))
+			}
+			delete(edges[from], eliminated)
+		}
+		delete(edges, eliminated)
+	}
+
+	return edges[start][end]
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func eliminationCost(edges map[int]map[int]*ast.Node, state int) int {
+	in := 0
+	for from, outgoing := range edges {
+		if _, ok := outgoing[state]; ok && from != state {
+			in++
+		}
+	}
+	out := len(edges[state])
+	if _, ok := edges[state][state]; ok {
+		out--
+	}
+	return in * out
+}
+
+// symbolsNode записывает множество символов перехода одним узлом: символом,
+// классом из их интервалов, отрицанием класса или точкой.
+func (dfa *DFA) symbolsNode(symbols []rune) *ast.Node {
+	set := make(map[rune]bool, len(symbols))
+	for _, symbol := range symbols {
+		set[symbol] = true
+	}
+
+	ranges := []charclass.Range{}
+	negated := set[charclass.Other]
+	for _, rng := range dfa.Alphabet.Explicit() {
+		if set[rng.Lo] != negated {
+			ranges = append(ranges, rng)
+		}
+	}
+	if !negated {
+		// символы вне алфавита бывают у автоматов, собранных вручную
+		for _, symbol := range symbols {
+			if !dfa.Alphabet.Has(symbol) {
+				ranges = append(ranges, charclass.Range{Lo: symbol, Hi: symbol})
+			}
+		}
+	}
+
+	if negated && len(ranges) == 0 {
+		return &ast.Node{Type: ast.NodeAny}
+	}
+	return classNode(ranges, negated)
+}
+
+// classNode записывает диапазоны классом, склеивая соседние; класс из одного
+// символа без отрицания - символом.
+func classNode(ranges []charclass.Range, negated bool) *ast.Node {
+	sorted := append([]charclass.Range{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Lo < sorted[j].Lo
+	})
+
+	class := charclass.Class{Negated: negated}
+	for _, rng := range sorted {
+		last := len(class.Ranges) - 1
+		if last >= 0 && class.Ranges[last].Hi+1 >= rng.Lo {
+			class.Ranges[last].Hi = max(class.Ranges[last].Hi, rng.Hi)
+			continue
+		}
+		class.Ranges = append(class.Ranges, rng)
+	}
+
+	if !negated && len(class.Ranges) == 1 && class.Ranges[0].Lo == class.Ranges[0].Hi {
+		return &ast.Node{Type: ast.NodeLiteral, Symbol: class.Ranges[0].Lo}
+	}
+	return &ast.Node{Type: ast.NodeClass, Class: class}
+}
+
+func emptyNode() *ast.Node {
+	return &ast.Node{Type: ast.NodeEmpty}
+}
+
+func isNullable(node *ast.Node) bool {
+	return node.Type == ast.NodeEmpty || node.Type == ast.NodeStar || node.Type == ast.NodeOptional
+}
+
+// alternation объединяет выражения; nil означает пустое множество.
+func alternation(a, b *ast.Node) *ast.Node {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	branches := []*ast.Node{}
+	seen := make(map[string]bool)
+	hasEmpty := false
+	for _, node := range []*ast.Node{a, b} {
+		parts := []*ast.Node{node}
+		switch node.Type {
+		case ast.NodeAlt:
+			parts = node.Children
+		case ast.NodeOptional:
+			parts = []*ast.Node{node.Children[0]}
+			hasEmpty = true
+		}
+		for _, part := range parts {
+			if part.Type == ast.NodeEmpty {
+				hasEmpty = true
+				continue
+			}
+			if key := part.String(); !seen[key] {
+				seen[key] = true
+				branches = append(branches, part)
+			}
+		}
+	}
+	branches = mergeSymbols(branches)
+
+	var result *ast.Node
+	switch len(branches) {
+	case 0:
+		return emptyNode()
+	case 1:
+		result = branches[0]
+	default:
+		result = &ast.Node{Type: ast.NodeAlt, Children: branches}
+	}
+
+	if hasEmpty && !isNullable(result) {
+		result = &ast.Node{Type: ast.NodeOptional, Children: []*ast.Node{result}}
+	}
+	return result
+}
+
+// mergeSymbols собирает ветки-символы и ветки-классы без отрицания в один класс.
+func mergeSymbols(branches []*ast.Node) []*ast.Node {
+	ranges := []charclass.Range{}
+	count := 0
+	rest := []*ast.Node{}
+	for _, branch := range branches {
+		switch {
+		case branch.Type == ast.NodeLiteral:
+			ranges = append(ranges, charclass.Range{Lo: branch.Symbol, Hi: branch.Symbol})
+			count++
+		case branch.Type == ast.NodeClass && !branch.Class.Negated:
+			ranges = append(ranges, branch.Class.Ranges...)
+			count++
+		default:
+			rest = append(rest, branch)
+		}
+	}
+
+	if count < 2 {
+		return branches
+	}
+
+	return append([]*ast.Node{classNode(ranges, false)}, rest...)
+}
+
+// concatenation соединяет выражения; если среди них есть nil, результат nil.
+func concatenation(nodes ...*ast.Node) *ast.Node {
+	items := []*ast.Node{}
+	for _, node := range nodes {
+		switch {
+		case node == nil:
+			return nil
+		case node.Type == ast.NodeEmpty:
+		case node.Type == ast.NodeConcat:
+			items = append(items, node.Children...)
+		default:
+			items = append(items, node)
+		}
+	}
+
+	// x x* и x* x записываются как x+
+	for i := 0; i+1 < len(items); i++ {
+		left, right := items[i], items[i+1]
+		switch {
+		case right.Type == ast.NodeStar && right.Children[0].String() == left.String():
+			items[i] = &ast.Node{Type: ast.NodePlus, Children: []*ast.Node{left}}
+		case left.Type == ast.NodeStar && left.Children[0].String() == right.String():
+			items[i] = &ast.Node{Type: ast.NodePlus, Children: []*ast.Node{right}}
+		default:
+			continue
+		}
+		items = append(items[:i+1], items[i+2:]...)
+	}
+
+	switch len(items) {
+	case 0:
+		return emptyNode()
+	case 1:
+		return items[0]
+	}
+	return &ast.Node{Type: ast.NodeConcat, Children: items}
+}
+
+// star строит замыкание Клини; для отсутствующей петли (nil) это пустая строка.
+func star(node *ast.Node) *ast.Node {
+	if node == nil || node.Type == ast.NodeEmpty {
+		return emptyNode()
+	}
+	switch node.Type {
+	case ast.NodeStar:
+		return node
+	case ast.NodePlus, ast.NodeOptional:
+		node = node.Children[0]
+	}
+	return &ast.Node{Type: ast.NodeStar, Children: []*ast.Node{node}}
+}
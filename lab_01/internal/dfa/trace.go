@@ -0,0 +1,319 @@
+package dfa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	nfa_pkg "github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+)
+
+// TraceStep - один шаг построения подмножеств: из состояния From по символу
+// Symbol получено move (Move) и его эпсилон-замыкание (Closure). To - состояние
+// ДКА для замыкания (-1, если замыкание пусто и перехода нет), New - оно
+// появилось на этом шаге.
+type TraceStep struct {
+	From    int
+	Symbol  rune
+	Move    []int
+	Closure []int
+	To      int
+	New     bool
+}
+
+// Trace - протокол построения подмножеств в порядке обработки состояний.
+type Trace struct {
+	Start    []int // эпсилон-замыкание начальных состояний НКА, состояние 0
+	Alphabet charclass.Alphabet
+	Steps    []TraceStep
+	Final    map[int]bool
+}
+
+// BuildWithTrace - то же, что Build, но дополнительно возвращает протокол
+// построения для отчета (Markdown, LaTeX) и покадровой визуализации (Frames).
+func BuildWithTrace(nfa *nfa_pkg.NFA) (*DFA, *Trace) {
+	trace := &Trace{}
+	dfa := build(nfa, trace)
+
+	trace.Start = sortedIDs(dfa.States[dfa.Start].NFAStates)
+	trace.Alphabet = dfa.Alphabet
+	trace.Final = make(map[int]bool)
+	for id, state := range dfa.States {
+		if state.IsFinal {
+			trace.Final[id] = true
+		}
+	}
+	return dfa, trace
+}
+
+func (t *Trace) add(step TraceStep) {
+	if t != nil {
+		t.Steps = append(t.Steps, step)
+	}
+}
+
+func sortedIDs(set map[int]bool) []int {
+	ids := make([]int, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// formatSet записывает множество состояний НКА как {1,3,5}.
+func formatSet(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// sets возвращает множества состояний НКА для состояний ДКА по порядку номеров.
+func (t *Trace) sets() [][]int {
+	sets := [][]int{t.Start}
+	for _, step := range t.Steps {
+		if step.New {
+			sets = append(sets, step.Closure)
+		}
+	}
+	return sets
+}
+
+func (t *Trace) symbol(symbol rune) string {
+	return charclass.Format([]rune{symbol}, t.Alphabet)
+}
+
+// cells возвращает строки протокола без разметки: шаг, состояние, символ,
+// move, замыкание и результат. Пустое множество записывается как ∅.
+func (t *Trace) cells() [][]string {
+	sets := t.sets()
+	rows := [][]string{}
+	for i, step := range t.Steps {
+		row := []string{
+			fmt.Sprint(i + 1),
+			fmt.Sprintf("%d = %s", step.From, formatSet(sets[step.From])),
+			t.symbol(step.Symbol),
+			"∅",
+			"∅",
+			"нет перехода",
+		}
+		if step.To >= 0 {
+			row[3] = formatSet(step.Move)
+			row[4] = formatSet(step.Closure)
+			row[5] = fmt.Sprintf("состояние %d", step.To)
+			if step.New {
+				row[5] = fmt.Sprintf("новое состояние %d", step.To)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// table возвращает таблицу переходов ДКА: состояние (-> у начального, * у
+// заключительных) и переход по каждому символу, "-" - перехода нет.
+func (t *Trace) table() ([]string, [][]string) {
+	header := []string{"Состояние"}
+	for _, symbol := range t.Alphabet.Symbols() {
+		header = append(header, t.symbol(symbol))
+	}
+
+	transitions := make(map[int]map[rune]int)
+	for _, step := range t.Steps {
+		if step.To >= 0 {
+			if transitions[step.From] == nil {
+				transitions[step.From] = make(map[rune]int)
+			}
+			transitions[step.From][step.Symbol] = step.To
+		}
+	}
+
+	rows := [][]string{}
+	for id, set := range t.sets() {
+		name := fmt.Sprintf("%d = %s", id, formatSet(set))
+		if id == 0 {
+			name = "→ " + name
+		}
+		if t.Final[id] {
+			name = "* " + name
+		}
+
+		row := []string{name}
+		for _, symbol := range t.Alphabet.Symbols() {
+			cell := "-"
+			if to, ok := transitions[id][symbol]; ok {
+				cell = fmt.Sprint(to)
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+var traceHeader = []string{"Шаг", "Состояние ДКА", "Символ", "move", "ε-замыкание", "Результат"}
+
+// Markdown записывает протокол и итоговую таблицу переходов таблицами Markdown.
+func (t *Trace) Markdown() string {
+	var builder strings.Builder
+
+	writeRow := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, cell := range cells {
+			escaped[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		builder.WriteString("| " + strings.Join(escaped, " | ") + " |\n")
+	}
+	writeTable := func(header []string, rows [][]string) {
+		writeRow(header)
+		builder.WriteString(strings.Repeat("|---", len(header)) + "|\n")
+		for _, row := range rows {
+			writeRow(row)
+		}
+	}
+
+	fmt.Fprintf(&builder, "Начальное состояние: 0 = ε-замыкание = %s\n\n", formatSet(t.Start))
+	writeTable(traceHeader, t.cells())
+	builder.WriteString("\n")
+	writeTable(t.table())
+
+	return builder.String()
+}
+
+var latexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"$", `\$`,
+	"&", `\&`,
+	"#", `\#`,
+	"_", `\_`,
+	"%", `\%`,
+	"^", `\textasciicircum{}`,
+	"~", `\textasciitilde{}`,
+	"ε", `$\varepsilon$`,
+	"∅", `$\varnothing$`,
+	"→", `$\to$`,
+)
+
+// LaTeX записывает протокол и итоговую таблицу переходов окружениями tabular.
+func (t *Trace) LaTeX() string {
+	var builder strings.Builder
+
+	writeRow := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, cell := range cells {
+			escaped[i] = latexReplacer.Replace(cell)
+		}
+		builder.WriteString("  " + strings.Join(escaped, " & ") + ` \\` + "\n")
+	}
+	writeTable := func(header []string, rows [][]string) {
+		builder.WriteString(`\begin{tabular}{|` + strings.Repeat("l|", len(header)) + "}\n")
+		builder.WriteString("  \\hline\n")
+		writeRow(header)
+		builder.WriteString("  \\hline\n")
+		for _, row := range rows {
+			writeRow(row)
+		}
+		builder.WriteString("  \\hline\n")
+		builder.WriteString(`\end{tabular}` + "\n")
+	}
+
+	writeTable(traceHeader, t.cells())
+	builder.WriteString("\n")
+	writeTable(t.table())
+
+	return builder.String()
+}
+
+// Frames возвращает кадры Graphviz: начальное состояние и по кадру на каждый
+// шаг протокола с ДКА, построенным к этому моменту. Состояния подписаны
+// множествами состояний НКА, обрабатываемое выделено красным, полученное на
+// шаге - синим.
+func (t *Trace) Frames() []string {
+	sets := t.sets()
+	partial := &DFA{States: make(map[int]*State), Alphabet: t.Alphabet}
+	addState := func(id int) {
+		nfaStates := make(map[int]bool)
+		for _, nfaStateID := range sets[id] {
+			nfaStates[nfaStateID] = true
+		}
+		partial.States[id] = NewState(id, nfaStates, t.Final[id])
+	}
+
+	addState(0)
+	frames := []string{partial.traceFrame(-1, -1, fmt.Sprintf("Начальное состояние: ε-замыкание = %s", formatSet(t.Start)))}
+
+	for i, step := range t.Steps {
+		description := fmt.Sprintf("Шаг %d: move(%s, %s) = ∅", i+1, formatSet(sets[step.From]), t.symbol(step.Symbol))
+		if step.To >= 0 {
+			if step.New {
+				addState(step.To)
+			}
+			partial.States[step.From].Transitions[step.Symbol] = step.To
+			description = fmt.Sprintf("Шаг %d: move(%s, %s) = %s, ε-замыкание = %s", i+1, formatSet(sets[step.From]), t.symbol(step.Symbol), formatSet(step.Move), formatSet(step.Closure))
+		}
+
+		frames = append(frames, partial.traceFrame(step.From, step.To, description))
+	}
+
+	return frames
+}
+
+func (dfa *DFA) traceFrame(current, target int, description string) string {
+	graph := "digraph DFA {\n"
+	graph += "  rankdir=LR;\n"
+	graph += "  node [shape = circle];\n"
+
+	graph += "  start [shape = point];\n"
+	graph += fmt.Sprintf("  start -> %d;\n", dfa.Start)
+
+	graph += dfa.statesToGraphviz()
+	graph += dfa.setLabelsToGraphviz()
+
+	if target >= 0 {
+		graph += fmt.Sprintf("  %d [color=blue, fontcolor=blue];\n", target)
+	}
+	if current >= 0 {
+		graph += fmt.Sprintf("  %d [color=red, fontcolor=red];\n", current)
+	}
+
+	graph += "  labelloc=\"t\";\n"
+	graph += fmt.Sprintf("  label=\"%s\";\n", charclass.EscapeDOT(description))
+
+	graph += dfa.edgesToGraphviz()
+
+	graph += "}\n"
+	return graph
+}
+
+// setLabelsToGraphviz подписывает состояния множествами состояний НКА.
+func (dfa *DFA) setLabelsToGraphviz() string {
+	graph := ""
+	for _, id := range sortedKeys(dfa.States) {
+		graph += fmt.Sprintf("  %d [label=\"%s\"];\n", id, formatSet(sortedIDs(dfa.States[id].NFAStates)))
+	}
+	return graph
+}
+
+// ToGraphvizWithSets - то же, что ToGraphviz, но состояния подписаны
+// множествами состояний НКА ({1,3,5}), из которых они получены.
+func (dfa *DFA) ToGraphvizWithSets() string {
+	graph := "digraph DFA {\n"
+	graph += "  rankdir=LR;\n"
+	graph += "  node [shape = circle];\n"
+
+	graph += "  start [shape = point];\n"
+	graph += fmt.Sprintf("  start -> %d;\n", dfa.Start)
+
+	graph += dfa.statesToGraphviz()
+	graph += dfa.setLabelsToGraphviz()
+
+	graph += dfa.edgesToGraphviz()
+
+	graph += "}\n"
+	return graph
+}
@@ -1,6 +1,12 @@
 package infixToPostix
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/nfa"
+)
 
 func TestFillConcateneteChars(t *testing.T) {
 	var tests = []struct {
@@ -11,9 +17,21 @@ func TestFillConcateneteChars(t *testing.T) {
 		{"(a(b|d))*", "(a.(b|d))*"},
 		{"a(bb)+c", "a.(b.b)+.c"},
 		{"abc", "a.b.c"},
-		{"((a.b.c))", "((a.b.c))"},
+		{"((a.b.c))", "((a.[^].b.[^].c))"},
 		{"(a(b|c)*d)*((ad)*c)", "(a.(b|c)*.d)*.((a.d)*.c)"},
 		{"((0|1)(0|1)(0|1))*", "((0|1).(0|1).(0|1))*"},
+		{"[a-z][a-z0-9_]*", "[a-z].[a-z0-9_]*"},
+		{"a[^\"]b", "a.[^\"].b"},
+		{"([01])+[.]", "([01])+.[.]"},
+		{`a\+b`, `a.\+.b`},
+		{`\(\)\n`, `\(.\).\n`},
+		{"[0-9]{4}-", "[0-9]{4}.-"},
+		{"a{2,5}b", "a{2,5}.b"},
+		{"(ab){2,}c", "(a.b){2,}.c"},
+		{"a?b", "a?.b"},
+		{"(αβ)*γ", "(α.β)*.γ"},
+		{"привет(, мир)?", "п.р.и.в.е.т.(,. .м.и.р)?"},
+		{"[а-яё]+_1", "[а-яё]+._.1"},
 	}
 
 	for _, tt := range tests {
@@ -33,10 +51,34 @@ func TestTransform(t *testing.T) {
 		{"(a(b|d))*", "abd|.*"},
 		{"a(bb)+c", "abb.+.c."},
 		{"abc", "ab.c."},
-		{"((a.b.c))", "ab.c."},
-		{"a.(b.b)+.c", "abb.+.c."},
+		{"((a.b.c))", "a[^].b.[^].c."},
+		{"a.(b.b)+.c", "a[^].b[^].b.+.[^].c."},
 		{"(a(b|c)*d)*((ad)*c)", "abc|*.d.*ad.*c.."},
-		{"((0|1).(0|1).(0|1))*", "01|01|.01|.*"},
+		{"((0|1).(0|1).(0|1))*", "01|[^].01|.[^].01|.*"},
+		{".*a", "[^]*a."},
+		{`\..`, `\.[^].`},
+		{"[a-z][a-z0-9_]*", "[a-z][a-z0-9_]*."},
+		{"x([^|*]|y)+", "x[^|*]y|+."},
+		{`1\+2\.5`, `1\+.2.\..5.`},
+		{`(a|\|)*\\`, `a\||*\\.`},
+		{"[0-9]{4}", "[0-9]{4}"},
+		{"a{2,5}b", "a{2,5}b."},
+		{"x(ab){2,}|c", "xab.{2,}.c|"},
+		{"a?b", "a?b."},
+		{"(αβ)*γ", "αβ.*γ."},
+		{"да|нет", "да.не.т.|"},
+		{"ε|я", "εя|"},
+		{`\ε|я`, `\εя|`},
+		{"[a-z]+&!(if|else)", "[a-z]+if.el.s.e.|!&"},
+		{"!a*b", "a*!b."},
+		{"a!b|c&d", "ab!.cd&|"},
+		{"!!a", "a!!"},
+		{"(a|)b", "aε|b."},
+		{"a||b", "aε|b|"},
+		{"a|", "aε|"},
+		{"|a", "εa|"},
+		{"()", "ε"},
+		{"()*a", "ε*a."},
 	}
 
 	for _, tt := range tests {
@@ -46,3 +88,88 @@ func TestTransform(t *testing.T) {
 		}
 	}
 }
+
+func TestTransformBuildsNFA(t *testing.T) {
+	var tests = []struct {
+		input    string
+		accepted []string
+		rejected []string
+	}{
+		{"(a|)b", []string{"ab", "b"}, []string{"", "a"}},
+		{"a||b", []string{"a", "b", ""}, []string{"ab"}},
+		{"a|", []string{"a", ""}, []string{"aa"}},
+		{"()", []string{""}, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		automaton := nfa.Build(Transform(tt.input))
+		for _, input := range tt.accepted {
+			if _, accepted := automaton.Simulate(input); !accepted {
+				t.Errorf("Input: %s, строка %q должна допускаться", tt.input, input)
+			}
+		}
+		for _, input := range tt.rejected {
+			if _, accepted := automaton.Simulate(input); accepted {
+				t.Errorf("Input: %s, строка %q не должна допускаться", tt.input, input)
+			}
+		}
+	}
+}
+
+func TestToPostfix(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected string
+	}{
+		{"(ab)*c", "ab.*c."},
+		{"a.(b.b)+.c", "a[^].b[^].b.+.[^].c."},
+		{"[a-z]{2,}", "[a-z]{2,}"},
+		{"a.?", "a[^]?."},
+		{"(a|)b", "aε|b."},
+		{"a||b", "aε|b|"},
+		{"()*", "ε*"},
+		{"a.|", "a[^].ε|"},
+	}
+
+	for _, tt := range tests {
+		actual, err := ToPostfix(tt.input)
+		if err != nil {
+			t.Errorf("Input: %s, неожиданная ошибка: %v", tt.input, err)
+			continue
+		}
+		if actual != tt.expected {
+			t.Errorf("Input: %s, Expected: %s, Actual %s", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestToPostfixErrors(t *testing.T) {
+	var tests = []struct {
+		input  string
+		offset int
+		reason string
+	}{
+		{"(ab", 0, lexer.ReasonUnbalancedParen},
+		{"a(b(c)", 1, lexer.ReasonUnbalancedParen},
+		{"ab)", 2, lexer.ReasonUnbalancedParen},
+		{"*a", 0, lexer.ReasonDanglingOperator},
+		{"a(+b)", 2, lexer.ReasonDanglingOperator},
+		{"ж({2}", 2, lexer.ReasonDanglingOperator},
+		{"(|+)", 2, lexer.ReasonDanglingOperator},
+		{"a{3,1}", 1, "нижняя граница повторения больше верхней"},
+		{"[a-", 0, "незакрытый класс символов"},
+	}
+
+	for _, tt := range tests {
+		_, err := ToPostfix(tt.input)
+
+		var syntaxErr *lexer.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Input: %s, ожидалась синтаксическая ошибка, получено: %v", tt.input, err)
+			continue
+		}
+		if syntaxErr.Offset != tt.offset || syntaxErr.Reason != tt.reason {
+			t.Errorf("Input: %s, Expected: %d %s, Actual: %d %s", tt.input, tt.offset, tt.reason, syntaxErr.Offset, syntaxErr.Reason)
+		}
+	}
+}
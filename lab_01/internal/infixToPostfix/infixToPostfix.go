@@ -1,77 +1,166 @@
 package infixToPostix
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+)
 
 const (
-	maxPriority = 4
+	maxPriority = 6
 )
 
-func fillConcatenateChars(infix string) string {
+func tokenize(infix string) []lexer.Token {
+	tokens := lexer.NewLexer(infix).Tokenize()
+	// последний токен - EOF или ERROR
+	return tokens[:len(tokens)-1]
+}
+
+// render записывает токены в формате постфиксной записи, где точка занята
+// конкатенацией, поэтому любой символ записывается как [^].
+func render(tokens []lexer.Token) string {
 	var result strings.Builder
-	n := len(infix)
+	for _, tok := range tokens {
+		if tok.Type == lexer.TokenAny {
+			result.WriteString("[^]")
+			continue
+		}
+		result.WriteString(tok.Literal)
+	}
+	return result.String()
+}
+
+func fillConcatenateChars(infix string) string {
+	return render(insertConcatenateChars(tokenize(infix)))
+}
+
+func insertConcatenateChars(tokens []lexer.Token) []lexer.Token {
+	result := make([]lexer.Token, 0, len(tokens))
+	n := len(tokens)
 
 	for i := 0; i < n; i++ {
-		result.WriteByte(infix[i])
+		result = append(result, tokens[i])
 
-		if i+1 < n && shouldAddConcatenateChar(infix[i], infix[i+1]) {
-			result.WriteByte('.')
+		if i+1 < n && shouldAddConcatenateChar(tokens[i], tokens[i+1]) {
+			result = append(result, lexer.Token{Type: lexer.TokenConcat, Literal: "."})
 		}
 	}
 
-	return result.String()
+	return result
 }
 
-func shouldAddConcatenateChar(a, b byte) bool {
-	return (isLetterOrDigit(a) && isLetterOrDigit(b)) ||
-		(isLetterOrDigit(a) && b == '(') ||
-		(a == ')' && isLetterOrDigit(b)) ||
-		((a == '*' || a == '+') && (isLetterOrDigit(b) || b == '(')) || (a == ')' && b == '(')
+// insertEmptyOperands подставляет ε на место пустой ветви альтернативы и
+// пустой группы: (a|)b -> (a|ε)b, a|| -> a|ε|ε, () -> (ε), чтобы у каждого
+// оператора в постфиксной записи были операнды.
+func insertEmptyOperands(tokens []lexer.Token) []lexer.Token {
+	epsilon := lexer.Token{Type: lexer.TokenEpsilon, Literal: "ε"}
+	result := make([]lexer.Token, 0, len(tokens))
+
+	for i := 0; i <= len(tokens); i++ {
+		opens := i == 0 || tokens[i-1].Type == lexer.TokenLParen || tokens[i-1].Type == lexer.TokenAlt
+		closes := i == len(tokens) || tokens[i].Type == lexer.TokenRParen || tokens[i].Type == lexer.TokenAlt
+		group := i > 0 && i < len(tokens) && tokens[i-1].Type == lexer.TokenLParen && tokens[i].Type == lexer.TokenRParen
+		alt := (i > 0 && tokens[i-1].Type == lexer.TokenAlt) || (i < len(tokens) && tokens[i].Type == lexer.TokenAlt)
+		if opens && closes && (group || alt) {
+			result = append(result, epsilon)
+		}
+
+		if i < len(tokens) {
+			result = append(result, tokens[i])
+		}
+	}
+
+	return result
+}
+
+// shouldAddConcatenateChar - префиксный ! начинает операнд так же, как открывающая скобка.
+func shouldAddConcatenateChar(a, b lexer.Token) bool {
+	startsOperand := b.Type == lexer.TokenLParen || b.Type == lexer.TokenNot
+	return (isOperand(a) && isOperand(b)) ||
+		(isOperand(a) && startsOperand) ||
+		(a.Type == lexer.TokenRParen && isOperand(b)) ||
+		(isQuantifier(a) && (isOperand(b) || startsOperand)) ||
+		(a.Type == lexer.TokenRParen && startsOperand)
+}
+
+func isQuantifier(tok lexer.Token) bool {
+	return tok.Type == lexer.TokenStar || tok.Type == lexer.TokenPlus ||
+		tok.Type == lexer.TokenQuestion || tok.Type == lexer.TokenRepeat
 }
 
-func isLetterOrDigit(c byte) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+// isOperand - служебные символы лексер выделяет в отдельные токены, поэтому любой
+// TokenSymbol (буква любого алфавита, цифра, пунктуация или экранированный
+// символ) является операндом, как и класс, точка, ε и якоря.
+func isOperand(tok lexer.Token) bool {
+	return tok.Type == lexer.TokenClass || tok.Type == lexer.TokenSymbol ||
+		tok.Type == lexer.TokenAny || tok.Type == lexer.TokenEpsilon ||
+		tok.Type == lexer.TokenBegin || tok.Type == lexer.TokenEnd
 }
 
 var specialCharsPriorityMap = map[rune]int{
 	'(': 1,
 	'|': 2,
-	'.': 3,
-	'?': 4,
-	'*': 4,
-	'+': 4,
+	'&': 3,
+	'.': 4,
+	'!': 5,
+	'?': 6,
+	'*': 6,
+	'+': 6,
+	'{': 6,
 }
 
-func priorityOf(r rune) int {
-	if priority, ok := specialCharsPriorityMap[r]; ok {
+func priorityOf(tok lexer.Token) int {
+	if isOperand(tok) {
+		return maxPriority + 1
+	}
+	if priority, ok := specialCharsPriorityMap[[]rune(tok.Literal)[0]]; ok {
 		return priority
 	}
 	return maxPriority + 1
 }
 
+// ToPostfix - как Transform, но выражение разбирается парсером ast.Parse, поэтому
+// вместо заведомо некорректной постфиксной записи возвращается *lexer.SyntaxError.
+func ToPostfix(infix string) (string, error) {
+	node, err := ast.Parse(infix)
+	if err != nil {
+		return "", err
+	}
+	return node.Postfix(), nil
+}
+
 func Transform(infix string) string {
-	infix = fillConcatenateChars(infix)
+	return toPostfix(tokenize(infix))
+}
+
+func toPostfix(tokens []lexer.Token) string {
+	tokens = insertConcatenateChars(insertEmptyOperands(tokens))
 
-	postfix := []rune{}
-	stack := []rune{}
+	postfix := []lexer.Token{}
+	stack := []lexer.Token{}
 
-	for _, r := range infix {
-		switch r {
-		case '(':
-			stack = append(stack, r)
-		case ')':
-			for len(stack) > 0 && stack[len(stack)-1] != '(' {
+	for _, tok := range tokens {
+		switch tok.Type {
+		case lexer.TokenLParen:
+			stack = append(stack, tok)
+		case lexer.TokenRParen:
+			for len(stack) > 0 && stack[len(stack)-1].Type != lexer.TokenLParen {
 				postfix = append(postfix, stack[len(stack)-1])
 				stack = stack[:len(stack)-1]
 			}
 			if len(stack) > 0 {
 				stack = stack[:len(stack)-1]
 			}
+		case lexer.TokenNot:
+			// префиксный оператор еще не имеет операнда, поэтому ничего не выталкивает
+			stack = append(stack, tok)
 		default:
-			for len(stack) > 0 && priorityOf(stack[len(stack)-1]) >= priorityOf(r) {
+			for len(stack) > 0 && priorityOf(stack[len(stack)-1]) >= priorityOf(tok) {
 				postfix = append(postfix, stack[len(stack)-1])
 				stack = stack[:len(stack)-1]
 			}
-			stack = append(stack, r)
+			stack = append(stack, tok)
 		}
 
 	}
@@ -81,5 +170,5 @@ func Transform(infix string) string {
 		stack = stack[:len(stack)-1]
 	}
 
-	return string(postfix)
+	return render(postfix)
 }
@@ -0,0 +1,384 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+)
+
+const (
+	TokenEOF = iota
+	TokenERROR
+	TokenSymbol
+	TokenClass
+	TokenAny
+	TokenEpsilon
+	TokenBegin
+	TokenEnd
+	TokenConcat
+	TokenAlt
+	TokenAnd
+	TokenNot
+	TokenStar
+	TokenPlus
+	TokenQuestion
+	TokenRepeat
+	TokenLParen
+	TokenRParen
+	TokenFlags
+	TokenFlagGroup
+)
+
+const (
+	// Unbounded - значение Max у квантификатора {m,} без верхней границы.
+	Unbounded = -1
+	// MaxRepeat ограничивает число копий подавтомата при построении {m,n}.
+	MaxRepeat = 1000
+)
+
+// Флаги выражения, задаваемые как (?i) или (?i:...).
+const (
+	FlagIgnoreCase = 1 << iota
+)
+
+var flagLetters = map[rune]int{
+	'i': FlagIgnoreCase,
+}
+
+// ParseFlags переводит буквы флагов (например, "i") в битовую маску.
+func ParseFlags(letters string) (int, error) {
+	flags := 0
+	for _, letter := range letters {
+		flag, ok := flagLetters[letter]
+		if !ok {
+			return 0, fmt.Errorf("неизвестный флаг %q", letter)
+		}
+		flags |= flag
+	}
+	return flags, nil
+}
+
+// operators - служебные символы инфиксной записи. Точка в ней означает любой
+// символ, ε - пустую строку, ^ и $ - начало и конец текста, & - пересечение,
+// ! - дополнение, а конкатенация не записывается вовсе.
+var operators = map[rune]int{
+	'.': TokenAny,
+	'ε': TokenEpsilon,
+	'^': TokenBegin,
+	'$': TokenEnd,
+	'|': TokenAlt,
+	'&': TokenAnd,
+	'!': TokenNot,
+	'*': TokenStar,
+	'+': TokenPlus,
+	'?': TokenQuestion,
+	'(': TokenLParen,
+	')': TokenRParen,
+}
+
+// postfixOperators - служебные символы постфиксной записи (формат Transform):
+// точка - оператор конкатенации, а любой символ записывается классом [^].
+var postfixOperators = map[rune]int{
+	'.': TokenConcat,
+	'ε': TokenEpsilon,
+	'^': TokenBegin,
+	'$': TokenEnd,
+	'|': TokenAlt,
+	'&': TokenAnd,
+	'!': TokenNot,
+	'*': TokenStar,
+	'+': TokenPlus,
+	'?': TokenQuestion,
+	'(': TokenLParen,
+	')': TokenRParen,
+}
+
+type Token struct {
+	Type    int
+	Literal string
+	Pos     int
+	Symbol  rune
+	Class   charclass.Class
+	Min     int
+	Max     int
+	// FlagsOn и FlagsOff - включаемые и выключаемые флаги у TokenFlags и TokenFlagGroup
+	FlagsOn  int
+	FlagsOff int
+}
+
+const (
+	ReasonUnbalancedParen  = "несбалансированная скобка"
+	ReasonDanglingOperator = "оператор без операнда"
+	ReasonEmptyOperand     = "пустой операнд"
+)
+
+// SyntaxError - ошибка в регулярном выражении. Offset - номер символа (руны),
+// на котором обнаружена ошибка.
+type SyntaxError struct {
+	Offset int
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("позиция %d: %s", e.Offset, e.Reason)
+}
+
+type Lexer struct {
+	input     []rune
+	pos       int
+	operators map[rune]int
+}
+
+func NewLexer(input string) *Lexer {
+	return &Lexer{
+		input:     []rune(input),
+		operators: operators,
+	}
+}
+
+func NewPostfixLexer(input string) *Lexer {
+	return &Lexer{
+		input:     []rune(input),
+		operators: postfixOperators,
+	}
+}
+
+func (l *Lexer) Tokenize() []Token {
+	tokens := []Token{}
+
+	tok := Token{Type: TokenSymbol}
+	for tok.Type != TokenEOF && tok.Type != TokenERROR {
+		tok = l.NextToken()
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// TokenizeStrict возвращает токены без завершающего EOF либо ошибку, если лексер
+// остановился на TokenERROR.
+func (l *Lexer) TokenizeStrict() ([]Token, error) {
+	tokens := l.Tokenize()
+	last := tokens[len(tokens)-1]
+	if last.Type == TokenERROR {
+		return nil, &SyntaxError{Offset: last.Pos, Reason: last.Literal}
+	}
+	return tokens[:len(tokens)-1], nil
+}
+
+func (l *Lexer) NextToken() Token {
+	if l.pos >= len(l.input) {
+		return Token{Type: TokenEOF, Pos: l.pos}
+	}
+
+	start := l.pos
+	ch := l.input[l.pos]
+
+	if ch == '(' && l.pos+1 < len(l.input) && l.input[l.pos+1] == '?' {
+		return l.readFlags()
+	}
+
+	if typ, ok := l.operators[ch]; ok {
+		l.pos++
+		return Token{Type: typ, Literal: string(ch), Pos: start}
+	}
+
+	if ch == '[' {
+		return l.readClass()
+	}
+
+	if ch == '{' {
+		return l.readRepeat()
+	}
+
+	if ch == '\\' {
+		symbol, errTok, ok := l.readEscape()
+		if !ok {
+			return errTok
+		}
+		return Token{Type: TokenSymbol, Literal: string(l.input[start:l.pos]), Pos: start, Symbol: symbol}
+	}
+
+	l.pos++
+	return Token{Type: TokenSymbol, Literal: string(ch), Pos: start, Symbol: ch}
+}
+
+func (l *Lexer) readClass() Token {
+	start := l.pos
+	l.pos++
+
+	class := charclass.Class{}
+	if l.pos < len(l.input) && l.input[l.pos] == '^' {
+		class.Negated = true
+		l.pos++
+	}
+
+	for {
+		if l.pos >= len(l.input) {
+			return Token{Type: TokenERROR, Literal: "незакрытый класс символов", Pos: start}
+		}
+
+		if l.input[l.pos] == ']' {
+			l.pos++
+			break
+		}
+
+		loPos := l.pos
+		lo, errTok, ok := l.readClassSymbol()
+		if !ok {
+			return errTok
+		}
+
+		hi := lo
+		if l.pos+1 < len(l.input) && l.input[l.pos] == '-' && l.input[l.pos+1] != ']' {
+			l.pos++
+			hi, errTok, ok = l.readClassSymbol()
+			if !ok {
+				return errTok
+			}
+			if hi < lo {
+				return Token{Type: TokenERROR, Literal: "неверный диапазон в классе символов", Pos: loPos}
+			}
+		}
+
+		class.Ranges = append(class.Ranges, charclass.Range{Lo: lo, Hi: hi})
+	}
+
+	return Token{Type: TokenClass, Literal: string(l.input[start:l.pos]), Pos: start, Class: class}
+}
+
+func (l *Lexer) readClassSymbol() (rune, Token, bool) {
+	if l.input[l.pos] == '\\' {
+		return l.readEscape()
+	}
+	l.pos++
+	return l.input[l.pos-1], Token{}, true
+}
+
+// readEscape разбирает последовательность, начинающуюся с обратной косой черты:
+// \n, \t, \r, \uXXXX или экранированный служебный символ вроде \* и \\.
+// Буква ε служебная (пустая строка), поэтому сама буква записывается как \ε.
+func (l *Lexer) readEscape() (rune, Token, bool) {
+	start := l.pos
+	l.pos++
+
+	if l.pos >= len(l.input) {
+		return 0, Token{Type: TokenERROR, Literal: "незавершенная escape-последовательность", Pos: start}, false
+	}
+
+	ch := l.input[l.pos]
+	l.pos++
+
+	switch ch {
+	case 'n':
+		return '\n', Token{}, true
+	case 't':
+		return '\t', Token{}, true
+	case 'r':
+		return '\r', Token{}, true
+	case 'ε':
+		return 'ε', Token{}, true
+	case 'u':
+		if l.pos+4 > len(l.input) {
+			return 0, Token{Type: TokenERROR, Literal: "ожидалось 4 шестнадцатеричные цифры после \\u", Pos: start}, false
+		}
+		code, err := strconv.ParseUint(string(l.input[l.pos:l.pos+4]), 16, 32)
+		if err != nil {
+			return 0, Token{Type: TokenERROR, Literal: "ожидалось 4 шестнадцатеричные цифры после \\u", Pos: start}, false
+		}
+		l.pos += 4
+		return rune(code), Token{}, true
+	}
+
+	if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+		return 0, Token{Type: TokenERROR, Literal: "неизвестная escape-последовательность", Pos: start}, false
+	}
+
+	return ch, Token{}, true
+}
+
+// readFlags разбирает (?флаги) и (?флаги:, например (?i), (?-i) или (?i:.
+func (l *Lexer) readFlags() Token {
+	start := l.pos
+	l.pos += 2
+
+	tok := Token{Type: TokenFlags, Pos: start}
+	flags := &tok.FlagsOn
+	for {
+		if l.pos >= len(l.input) {
+			return Token{Type: TokenERROR, Literal: "незакрытая группа флагов", Pos: start}
+		}
+
+		ch := l.input[l.pos]
+		l.pos++
+
+		switch {
+		case ch == ')':
+		case ch == ':':
+			tok.Type = TokenFlagGroup
+		case ch == '-' && flags == &tok.FlagsOn:
+			flags = &tok.FlagsOff
+			continue
+		case flagLetters[ch] != 0:
+			*flags |= flagLetters[ch]
+			continue
+		default:
+			return Token{Type: TokenERROR, Literal: "неизвестный флаг", Pos: l.pos - 1}
+		}
+
+		tok.Literal = string(l.input[start:l.pos])
+		return tok
+	}
+}
+
+func (l *Lexer) readRepeat() Token {
+	start := l.pos
+	l.pos++
+
+	min, ok := l.readNumber()
+	if !ok {
+		return Token{Type: TokenERROR, Literal: "ожидалось число повторений", Pos: l.pos}
+	}
+
+	max := min
+	if l.pos < len(l.input) && l.input[l.pos] == ',' {
+		l.pos++
+		if l.pos < len(l.input) && l.input[l.pos] == '}' {
+			max = Unbounded
+		} else if max, ok = l.readNumber(); !ok {
+			return Token{Type: TokenERROR, Literal: "ожидалось число повторений", Pos: l.pos}
+		}
+	}
+
+	if l.pos >= len(l.input) || l.input[l.pos] != '}' {
+		return Token{Type: TokenERROR, Literal: "незакрытый квантификатор {", Pos: start}
+	}
+	l.pos++
+
+	if max != Unbounded && min > max {
+		return Token{Type: TokenERROR, Literal: "нижняя граница повторения больше верхней", Pos: start}
+	}
+	if min > MaxRepeat || max > MaxRepeat {
+		return Token{Type: TokenERROR, Literal: "слишком большое число повторений", Pos: start}
+	}
+
+	return Token{Type: TokenRepeat, Literal: string(l.input[start:l.pos]), Pos: start, Min: min, Max: max}
+}
+
+func (l *Lexer) readNumber() (int, bool) {
+	start := l.pos
+	for l.pos < len(l.input) && l.input[l.pos] >= '0' && l.input[l.pos] <= '9' {
+		l.pos++
+	}
+
+	if start == l.pos {
+		return 0, false
+	}
+
+	number, err := strconv.Atoi(string(l.input[start:l.pos]))
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
@@ -0,0 +1,238 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Token
+	}{
+		{
+			name:  "empty input",
+			input: "",
+			expected: []Token{
+				{Type: TokenEOF},
+			},
+		},
+		{
+			name:  "operators",
+			input: "(a|b)*.c+?",
+			expected: []Token{
+				{Type: TokenLParen, Literal: "(", Pos: 0},
+				{Type: TokenSymbol, Literal: "a", Pos: 1, Symbol: 'a'},
+				{Type: TokenAlt, Literal: "|", Pos: 2},
+				{Type: TokenSymbol, Literal: "b", Pos: 3, Symbol: 'b'},
+				{Type: TokenRParen, Literal: ")", Pos: 4},
+				{Type: TokenStar, Literal: "*", Pos: 5},
+				{Type: TokenAny, Literal: ".", Pos: 6},
+				{Type: TokenSymbol, Literal: "c", Pos: 7, Symbol: 'c'},
+				{Type: TokenPlus, Literal: "+", Pos: 8},
+				{Type: TokenQuestion, Literal: "?", Pos: 9},
+				{Type: TokenEOF, Pos: 10},
+			},
+		},
+		{
+			name:  "class with ranges",
+			input: "[a-z0-9_]",
+			expected: []Token{
+				{Type: TokenClass, Literal: "[a-z0-9_]", Pos: 0, Class: charclass.Class{
+					Ranges: []charclass.Range{{Lo: 'a', Hi: 'z'}, {Lo: '0', Hi: '9'}, {Lo: '_', Hi: '_'}},
+				}},
+				{Type: TokenEOF, Pos: 9},
+			},
+		},
+		{
+			name:  "negated class",
+			input: "[^\"-]x",
+			expected: []Token{
+				{Type: TokenClass, Literal: "[^\"-]", Pos: 0, Class: charclass.Class{
+					Negated: true,
+					Ranges:  []charclass.Range{{Lo: '"', Hi: '"'}, {Lo: '-', Hi: '-'}},
+				}},
+				{Type: TokenSymbol, Literal: "x", Pos: 5, Symbol: 'x'},
+				{Type: TokenEOF, Pos: 6},
+			},
+		},
+		{
+			name:  "escapes",
+			input: `\*\.\\\n\t\u0416`,
+			expected: []Token{
+				{Type: TokenSymbol, Literal: `\*`, Pos: 0, Symbol: '*'},
+				{Type: TokenSymbol, Literal: `\.`, Pos: 2, Symbol: '.'},
+				{Type: TokenSymbol, Literal: `\\`, Pos: 4, Symbol: '\\'},
+				{Type: TokenSymbol, Literal: `\n`, Pos: 6, Symbol: '\n'},
+				{Type: TokenSymbol, Literal: `\t`, Pos: 8, Symbol: '\t'},
+				{Type: TokenSymbol, Literal: `\u0416`, Pos: 10, Symbol: 'Ж'},
+				{Type: TokenEOF, Pos: 16},
+			},
+		},
+		{
+			name:  "escapes in class",
+			input: `[\]\-\n-\u0020]`,
+			expected: []Token{
+				{Type: TokenClass, Literal: `[\]\-\n-\u0020]`, Pos: 0, Class: charclass.Class{
+					Ranges: []charclass.Range{{Lo: ']', Hi: ']'}, {Lo: '-', Hi: '-'}, {Lo: '\n', Hi: ' '}},
+				}},
+				{Type: TokenEOF, Pos: 15},
+			},
+		},
+		{
+			name:  "unknown escape",
+			input: `a\q`,
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenERROR, Literal: "неизвестная escape-последовательность", Pos: 1},
+			},
+		},
+		{
+			name:  "trailing backslash",
+			input: `a\`,
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenERROR, Literal: "незавершенная escape-последовательность", Pos: 1},
+			},
+		},
+		{
+			name:  "repeats",
+			input: "a{4}b{2,}c{2,5}",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenRepeat, Literal: "{4}", Pos: 1, Min: 4, Max: 4},
+				{Type: TokenSymbol, Literal: "b", Pos: 4, Symbol: 'b'},
+				{Type: TokenRepeat, Literal: "{2,}", Pos: 5, Min: 2, Max: Unbounded},
+				{Type: TokenSymbol, Literal: "c", Pos: 9, Symbol: 'c'},
+				{Type: TokenRepeat, Literal: "{2,5}", Pos: 10, Min: 2, Max: 5},
+				{Type: TokenEOF, Pos: 15},
+			},
+		},
+		{
+			name:  "repeat bounds out of order",
+			input: "a{5,2}",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenERROR, Literal: "нижняя граница повторения больше верхней", Pos: 1},
+			},
+		},
+		{
+			name:  "repeat without number",
+			input: "a{,2}",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenERROR, Literal: "ожидалось число повторений", Pos: 2},
+			},
+		},
+		{
+			name:  "unterminated repeat",
+			input: "a{2",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenERROR, Literal: "незакрытый квантификатор {", Pos: 1},
+			},
+		},
+		{
+			name:  "non-ascii symbols",
+			input: "ж[а-я]*",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "ж", Pos: 0, Symbol: 'ж'},
+				{Type: TokenClass, Literal: "[а-я]", Pos: 1, Class: charclass.Class{
+					Ranges: []charclass.Range{{Lo: 'а', Hi: 'я'}},
+				}},
+				{Type: TokenStar, Literal: "*", Pos: 6},
+				{Type: TokenEOF, Pos: 7},
+			},
+		},
+		{
+			name:  "epsilon",
+			input: `ε|\ε`,
+			expected: []Token{
+				{Type: TokenEpsilon, Literal: "ε", Pos: 0},
+				{Type: TokenAlt, Literal: "|", Pos: 1},
+				{Type: TokenSymbol, Literal: `\ε`, Pos: 2, Symbol: 'ε'},
+				{Type: TokenEOF, Pos: 4},
+			},
+		},
+		{
+			name:  "intersection and complement",
+			input: "a&!b",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenAnd, Literal: "&", Pos: 1},
+				{Type: TokenNot, Literal: "!", Pos: 2},
+				{Type: TokenSymbol, Literal: "b", Pos: 3, Symbol: 'b'},
+				{Type: TokenEOF, Pos: 4},
+			},
+		},
+		{
+			name:  "flags",
+			input: "(?i)(?-i:a)",
+			expected: []Token{
+				{Type: TokenFlags, Literal: "(?i)", Pos: 0, FlagsOn: FlagIgnoreCase},
+				{Type: TokenFlagGroup, Literal: "(?-i:", Pos: 4, FlagsOff: FlagIgnoreCase},
+				{Type: TokenSymbol, Literal: "a", Pos: 9, Symbol: 'a'},
+				{Type: TokenRParen, Literal: ")", Pos: 10},
+				{Type: TokenEOF, Pos: 11},
+			},
+		},
+		{
+			name:  "unknown flag",
+			input: "(?x)",
+			expected: []Token{
+				{Type: TokenERROR, Literal: "неизвестный флаг", Pos: 2},
+			},
+		},
+		{
+			name:  "unterminated flags",
+			input: "a(?i",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenERROR, Literal: "незакрытая группа флагов", Pos: 1},
+			},
+		},
+		{
+			name:  "unterminated class",
+			input: "a[bc",
+			expected: []Token{
+				{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+				{Type: TokenERROR, Literal: "незакрытый класс символов", Pos: 1},
+			},
+		},
+		{
+			name:  "reversed range",
+			input: "[z-a]",
+			expected: []Token{
+				{Type: TokenERROR, Literal: "неверный диапазон в классе символов", Pos: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := NewLexer(tt.input).Tokenize()
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Input: %s, Expected: %v, Actual: %v", tt.input, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTokenizePostfix(t *testing.T) {
+	actual := NewPostfixLexer(`a\..[^].`).Tokenize()
+	expected := []Token{
+		{Type: TokenSymbol, Literal: "a", Pos: 0, Symbol: 'a'},
+		{Type: TokenSymbol, Literal: `\.`, Pos: 1, Symbol: '.'},
+		{Type: TokenConcat, Literal: ".", Pos: 3},
+		{Type: TokenClass, Literal: "[^]", Pos: 4, Class: charclass.Class{Negated: true}},
+		{Type: TokenConcat, Literal: ".", Pos: 7},
+		{Type: TokenEOF, Pos: 8},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected: %v, Actual: %v", expected, actual)
+	}
+}
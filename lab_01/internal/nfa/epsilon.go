@@ -0,0 +1,120 @@
+package nfa
+
+import "sort"
+
+// States возвращает все состояния, достижимые из Start и StartStates, по
+// возрастанию номеров.
+func (a *NFA) States() []*State {
+	visited := make(map[*State]bool)
+	stack := append([]*State{a.Start}, a.StartStates...)
+	states := []*State{}
+
+	for len(stack) > 0 {
+		state := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if state == nil || visited[state] {
+			continue
+		}
+		visited[state] = true
+		states = append(states, state)
+
+		for _, nextStates := range state.Transitions {
+			stack = append(stack, nextStates...)
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+	return states
+}
+
+// RemoveEpsilon строит эквивалентный автомат без эпсилон-переходов. Остаются
+// начальные состояния и состояния, в которые ведет переход по символу, с
+// прежними номерами. Переход q -a-> p появляется, если p достижимо из q по
+// эпсилон-переходам и одному переходу по a, а q заключительное, если из него
+// по эпсилон-переходам достижимо заключительное. Заключительных состояний может
+// быть несколько, поэтому End у результата не задан.
+func (a *NFA) RemoveEpsilon() *NFA {
+	copies := make(map[*State]*State)
+	queue := []*State{}
+	visit := func(state *State) *State {
+		if copied, ok := copies[state]; ok {
+			return copied
+		}
+		copied := NewState(state.ID)
+		copies[state] = copied
+		queue = append(queue, state)
+		return copied
+	}
+
+	result := &NFA{
+		Alphabet:    a.Alphabet,
+		AnchorStart: a.AnchorStart,
+		AnchorEnd:   a.AnchorEnd,
+	}
+	starts := a.StartStates
+	if len(starts) == 0 {
+		starts = []*State{a.Start}
+	}
+	for _, start := range starts {
+		result.StartStates = append(result.StartStates, visit(start))
+	}
+	result.Start = copies[a.Start]
+	if result.Start == nil {
+		result.Start = result.StartStates[0]
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		copied := copies[state]
+
+		for _, reached := range epsilonClosure(state) {
+			if reached.IsFinal {
+				copied.IsFinal = true
+			}
+			for _, symbol := range sortedSymbols(reached) {
+				if symbol == EPS {
+					continue
+				}
+				for _, nextState := range reached.Transitions[symbol] {
+					next := visit(nextState)
+					if !containsState(copied.Transitions[symbol], next) {
+						copied.Transitions[symbol] = append(copied.Transitions[symbol], next)
+					}
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// epsilonClosure - состояния, достижимые из state по эпсилон-переходам (вместе
+// с ним самим), в порядке обхода.
+func epsilonClosure(state *State) []*State {
+	visited := map[*State]bool{state: true}
+	closure := []*State{state}
+
+	for i := 0; i < len(closure); i++ {
+		for _, nextState := range closure[i].Transitions[EPS] {
+			if !visited[nextState] {
+				visited[nextState] = true
+				closure = append(closure, nextState)
+			}
+		}
+	}
+
+	return closure
+}
+
+func containsState(states []*State, state *State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
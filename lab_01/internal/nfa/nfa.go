@@ -3,9 +3,18 @@ package nfa
 import (
 	"fmt"
 	"sort"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
 )
 
-const EPS = 'ε'
+// EPS - метка эпсилон-перехода. Это не символ Unicode, поэтому экранированная
+// буква \ε в выражении остается обычным символом; на графе переход подписывается EPSLabel.
+const (
+	EPS      rune = -1
+	EPSLabel      = "ε"
+)
 
 type State struct {
 	ID          int
@@ -16,17 +25,22 @@ type State struct {
 type NFA struct {
 	Start       *State
 	End         *State
-	StartStates []*State // у NFA по постронию одно состояние start, впихиваю сюда массив для алгоритма Бржозовского, так как там после инверта мб несколько стартов
+	StartStates []*State           // у NFA по постронию одно состояние start, впихиваю сюда массив для алгоритма Бржозовского, так как там после инверта мб несколько стартов
+	Alphabet    charclass.Alphabet // символы выражения, по которым может не быть переходов (например, исключенные отрицанием)
+	AnchorStart bool               // выражение начинается с ^: при поиске совпадение только с начала текста
+	AnchorEnd   bool               // выражение заканчивается на $: при поиске совпадение только до конца текста
 }
 
-func (a *NFA) ExtractAlphabet() []rune {
-	alphabetMap := make(map[rune]bool)
+// ExtractAlphabet возвращает Alphabet вместе с символами переходов, которых в
+// нем нет (например, Other), как интервалами из одного символа.
+func (a *NFA) ExtractAlphabet() charclass.Alphabet {
+	extra := []rune{}
 
 	var traverse func(state *State)
 	traverse = func(state *State) {
 		for symbol := range state.Transitions {
-			if symbol != EPS {
-				alphabetMap[symbol] = true
+			if symbol != EPS && !a.Alphabet.Has(symbol) {
+				extra = append(extra, symbol)
 			}
 		}
 	}
@@ -57,13 +71,9 @@ func (a *NFA) ExtractAlphabet() []rune {
 		}
 	}
 
-	alphabet := make([]rune, 0, len(alphabetMap))
-	for symbol := range alphabetMap {
-		alphabet = append(alphabet, symbol)
-	}
-
+	alphabet := append(charclass.Singletons(extra...), a.Alphabet...)
 	sort.Slice(alphabet, func(i, j int) bool {
-		return alphabet[i] < alphabet[j]
+		return alphabet[i].Lo < alphabet[j].Lo
 	})
 
 	return alphabet
@@ -80,91 +90,243 @@ func New(start, end *State) *NFA {
 	return &NFA{Start: start, End: end, StartStates: []*State{}}
 }
 
+// Build - путь совместимости для постфиксной записи (результат Transform):
+// восстанавливает по ней дерево и строит автомат через FromAST. Некорректная
+// постфиксная запись приводит к панике с описанием ошибки.
 func Build(postfix string) *NFA {
-	stack := []*NFA{}
-	stateID := 0
-
-	for _, char := range postfix {
-		switch char {
-		case '.':
-			nfa2 := stack[len(stack)-1]
-			nfa1 := stack[len(stack)-2]
-			stack = stack[:len(stack)-2]
-
-			nfa1.End.Transitions[EPS] = append(nfa1.End.Transitions[EPS], nfa2.Start)
-
-			stack = append(stack, New(nfa1.Start, nfa2.End))
-		case '|':
-			nfa2 := stack[len(stack)-1]
-			nfa1 := stack[len(stack)-2]
-			stack = stack[:len(stack)-2]
-
-			start := NewState(stateID)
-			stateID++
-			end := NewState(stateID)
-			stateID++
-
-			start.Transitions[EPS] = append(start.Transitions[EPS], nfa1.Start, nfa2.Start)
-
-			nfa1.End.Transitions[EPS] = append(nfa1.End.Transitions[EPS], end)
-			nfa2.End.Transitions[EPS] = append(nfa2.End.Transitions[EPS], end)
-
-			stack = append(stack, New(start, end))
-		case '?':
-			nfa := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-
-			start := NewState(stateID)
-			stateID++
-			end := NewState(stateID)
-			stateID++
-
-			start.Transitions[EPS] = append(start.Transitions[EPS], nfa.Start, end)
-			nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], end)
-
-			stack = append(stack, New(start, end))
-		case '*':
-			nfa := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-
-			start := NewState(stateID)
-			stateID++
-			end := NewState(stateID)
-			stateID++
-
-			start.Transitions[EPS] = append(start.Transitions[EPS], nfa.Start, end)
-			nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], nfa.Start)
-			nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], end)
-
-			stack = append(stack, New(start, end))
-		case '+':
-			nfa := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-
-			start := NewState(stateID)
-			stateID++
-			end := NewState(stateID)
-			stateID++
-
-			start.Transitions[EPS] = append(start.Transitions[EPS], nfa.Start)
-			nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], nfa.Start)
-			nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], end)
-
-			stack = append(stack, New(start, end))
-		default:
-			start := NewState(stateID)
-			stateID++
-			end := NewState(stateID)
-			stateID++
-
-			start.Transitions[char] = append(start.Transitions[char], end)
-			stack = append(stack, New(start, end))
+	node, err := ast.FromPostfix(postfix)
+	if err != nil {
+		panic(fmt.Sprintf("некорректная постфиксная запись %q: %v", postfix, err))
+	}
+	return FromAST(node)
+}
+
+// FromAST строит автомат Томпсона по дереву выражения без пересечений и
+// дополнений (см. ast.IsExtended); для них нужен FromASTWith.
+func FromAST(node *ast.Node) *NFA {
+	return FromASTWith(node, nil)
+}
+
+// Extended строит фрагмент для подвыражения с & или ! (узлы And и Not), у
+// которого нет конструкции Томпсона. alphabet - алфавит всего выражения: по
+// символам из него, которых нет в подвыражении, фрагмент должен переходить так же,
+// как по charclass.Other. Фрагмент копируется в автомат, поэтому номера его
+// состояний могут быть любыми.
+type Extended func(node *ast.Node, alphabet charclass.Alphabet) *NFA
+
+// FromASTWith - то же, что FromAST, но узлы And и Not строятся через extended.
+func FromASTWith(node *ast.Node, extended Extended) *NFA {
+	b := &builder{alphabet: ast.Alphabet(node), extended: extended}
+
+	result := b.build(node)
+	result.StartStates = append(result.StartStates, result.Start)
+	result.End.IsFinal = true
+	result.Alphabet = b.alphabet
+	result.AnchorStart, result.AnchorEnd = ast.Anchors(node)
+	return result
+}
+
+// builder строит фрагменты автомата по Томпсону, выдавая состояниям сквозные номера.
+type builder struct {
+	stateID  int
+	alphabet charclass.Alphabet
+	extended Extended
+}
+
+func (b *builder) build(node *ast.Node) *NFA {
+	switch node.Type {
+	case ast.NodeLiteral, ast.NodeClass:
+		return b.symbols(node.EffectiveClass().Symbols(b.alphabet))
+	case ast.NodeAny:
+		return b.symbols(charclass.Class{Negated: true}.Symbols(b.alphabet))
+	case ast.NodeEmpty, ast.NodeBegin, ast.NodeEnd, ast.NodeFlags:
+		// якоря учитываются при поиске через AnchorStart и AnchorEnd, а флаги уже
+		// применены к символам и классам (EffectiveClass)
+		return b.empty()
+	case ast.NodeAnd, ast.NodeNot:
+		if b.extended == nil {
+			panic("пересечение и дополнение не строятся по Томпсону, используйте FromASTWith")
+		}
+		return b.copy(b.extended(node, b.alphabet))
+	case ast.NodeConcat:
+		result := b.build(node.Children[0])
+		for _, child := range node.Children[1:] {
+			result = b.concatenate(result, b.build(child))
+		}
+		return result
+	case ast.NodeAlt:
+		result := b.build(node.Children[0])
+		for _, child := range node.Children[1:] {
+			result = b.alternate(result, b.build(child))
+		}
+		return result
+	case ast.NodeStar:
+		return b.star(b.build(node.Children[0]))
+	case ast.NodePlus:
+		return b.plus(b.build(node.Children[0]))
+	case ast.NodeOptional:
+		return b.optional(b.build(node.Children[0]))
+	case ast.NodeRepeat:
+		return b.repeat(b.build(node.Children[0]), node.Min, node.Max)
+	}
+
+	// NodeGroup влияет только на разбор
+	return b.build(node.Children[0])
+}
+
+func (b *builder) newState() *State {
+	state := NewState(b.stateID)
+	b.stateID++
+	return state
+}
+
+func (b *builder) symbols(symbols []rune) *NFA {
+	start := b.newState()
+	end := b.newState()
+
+	for _, symbol := range symbols {
+		start.Transitions[symbol] = append(start.Transitions[symbol], end)
+	}
+
+	return New(start, end)
+}
+
+func (b *builder) empty() *NFA {
+	start := b.newState()
+	end := b.newState()
+
+	start.Transitions[EPS] = append(start.Transitions[EPS], end)
+
+	return New(start, end)
+}
+
+func (b *builder) concatenate(nfa1, nfa2 *NFA) *NFA {
+	nfa1.End.Transitions[EPS] = append(nfa1.End.Transitions[EPS], nfa2.Start)
+
+	return New(nfa1.Start, nfa2.End)
+}
+
+func (b *builder) alternate(nfa1, nfa2 *NFA) *NFA {
+	start := b.newState()
+	end := b.newState()
+
+	start.Transitions[EPS] = append(start.Transitions[EPS], nfa1.Start, nfa2.Start)
+
+	nfa1.End.Transitions[EPS] = append(nfa1.End.Transitions[EPS], end)
+	nfa2.End.Transitions[EPS] = append(nfa2.End.Transitions[EPS], end)
+
+	return New(start, end)
+}
+
+func (b *builder) optional(nfa *NFA) *NFA {
+	start := b.newState()
+	end := b.newState()
+
+	start.Transitions[EPS] = append(start.Transitions[EPS], nfa.Start, end)
+	nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], end)
+
+	return New(start, end)
+}
+
+func (b *builder) star(nfa *NFA) *NFA {
+	start := b.newState()
+	end := b.newState()
+
+	start.Transitions[EPS] = append(start.Transitions[EPS], nfa.Start, end)
+	nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], nfa.Start)
+	nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], end)
+
+	return New(start, end)
+}
+
+func (b *builder) plus(nfa *NFA) *NFA {
+	start := b.newState()
+	end := b.newState()
+
+	start.Transitions[EPS] = append(start.Transitions[EPS], nfa.Start)
+	nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], nfa.Start)
+	nfa.End.Transitions[EPS] = append(nfa.End.Transitions[EPS], end)
+
+	return New(start, end)
+}
+
+// repeat раскрывает {min,max} в конкатенацию копий фрагмента: min обязательных,
+// затем max-min необязательных, а при max = lexer.Unbounded последняя
+// обязательная копия замыкается плюсом (или весь фрагмент звездой при min = 0).
+func (b *builder) repeat(nfa *NFA, min, max int) *NFA {
+	count := max
+	if max == lexer.Unbounded {
+		count = min
+		if count == 0 {
+			count = 1
 		}
 	}
 
-	stack[0].StartStates = append(stack[0].StartStates, stack[0].Start)
-	stack[0].End.IsFinal = true
-	return stack[0]
+	if count == 0 {
+		return b.empty()
+	}
+
+	copies := []*NFA{nfa}
+	for len(copies) < count {
+		copies = append(copies, b.copy(nfa))
+	}
+
+	for i := range copies {
+		switch {
+		case max == lexer.Unbounded && min == 0:
+			copies[i] = b.star(copies[i])
+		case max == lexer.Unbounded && i == min-1:
+			copies[i] = b.plus(copies[i])
+		case i >= min:
+			copies[i] = b.optional(copies[i])
+		}
+	}
+
+	result := copies[0]
+	for _, next := range copies[1:] {
+		result = b.concatenate(result, next)
+	}
+	return result
+}
+
+// copy создает копию фрагмента с новыми номерами состояний. Фрагмент на стеке
+// построения замкнут: все его состояния достижимы из Start, а из End переходов нет.
+func (b *builder) copy(nfa *NFA) *NFA {
+	copies := make(map[*State]*State)
+
+	var visit func(state *State) *State
+	visit = func(state *State) *State {
+		if copied, ok := copies[state]; ok {
+			return copied
+		}
+
+		copied := b.newState()
+		copies[state] = copied
+
+		for _, symbol := range sortedSymbols(state) {
+			for _, nextState := range state.Transitions[symbol] {
+				copied.Transitions[symbol] = append(copied.Transitions[symbol], visit(nextState))
+			}
+		}
+
+		return copied
+	}
+
+	start := visit(nfa.Start)
+	return New(start, visit(nfa.End))
+}
+
+func sortedSymbols(state *State) []rune {
+	symbols := make([]rune, 0, len(state.Transitions))
+	for symbol := range state.Transitions {
+		symbols = append(symbols, symbol)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i] < symbols[j]
+	})
+
+	return symbols
 }
 
 func (a *NFA) ToGraphviz() string {
@@ -173,12 +335,35 @@ func (a *NFA) ToGraphviz() string {
 	graph += "  node [shape = circle];\n"
 
 	graph += "  start [shape = point];\n"
-	graph += fmt.Sprintf("  start -> %d;\n", a.Start.ID)
+	for _, state := range a.startStates() {
+		graph += fmt.Sprintf("  start -> %d;\n", state.ID)
+	}
 
-	graph += fmt.Sprintf("  %d [shape = doublecircle];\n", a.End.ID)
+	graph += a.statesToGraphviz()
 
+	graph += "}\n"
+	return graph
+}
+
+// startStates - начальные состояния: после обращения ДКА (Minimize) их
+// несколько, и все они в StartStates. Как и в dfa.Build, Start сюда не
+// добавляется: у обращенного ДКА без заключительных состояний начальных нет.
+func (a *NFA) startStates() []*State {
+	return a.StartStates
+}
+
+// statesToGraphviz рисует состояния и переходы, достижимые из начальных.
+func (a *NFA) statesToGraphviz() string {
+	graph := ""
+
+	alphabet := a.ExtractAlphabet()
 	visited := make(map[*State]bool)
-	stack := []*State{a.Start}
+	starts := a.startStates()
+	stack := make([]*State, 0, len(starts))
+	// в обратном порядке, чтобы первым обойти первое начальное
+	for i := len(starts) - 1; i >= 0; i-- {
+		stack = append(stack, starts[i])
+	}
 
 	for len(stack) > 0 {
 		state := stack[len(stack)-1]
@@ -189,20 +374,47 @@ func (a *NFA) ToGraphviz() string {
 		}
 		visited[state] = true
 
+		// заключительных состояний несколько, например после RemoveEpsilon
+		if state.IsFinal || state == a.End {
+			graph += fmt.Sprintf("  %d [shape = doublecircle];\n", state.ID)
+		}
 		graph += fmt.Sprintf("  %d [label=\"%d\"];\n", state.ID, state.ID)
 
-		for char, nextStates := range state.Transitions {
-			for _, nextState := range nextStates {
-				graph += fmt.Sprintf("  %d -> %d [label=\"%c\"];\n", state.ID, nextState.ID, char)
-				stack = append(stack, nextState)
-			}
+		for _, nextState := range state.Transitions[EPS] {
+			graph += fmt.Sprintf("  %d -> %d [label=\"%s\"];\n", state.ID, nextState.ID, EPSLabel)
+			stack = append(stack, nextState)
+		}
+
+		nextStates, symbols := groupTransitions(state)
+		for _, nextState := range nextStates {
+			graph += fmt.Sprintf("  %d -> %d [label=\"%s\"];\n", state.ID, nextState.ID, charclass.Label(symbols[nextState], alphabet))
+			stack = append(stack, nextState)
 		}
 	}
 
-	graph += "}\n"
 	return graph
 }
 
+// groupTransitions объединяет переходы по символам (кроме EPS), ведущие в одно
+// состояние, чтобы подписать ребро диапазоном, а не рисовать ребро на каждый символ.
+func groupTransitions(state *State) ([]*State, map[*State][]rune) {
+	nextStates := []*State{}
+	grouped := make(map[*State][]rune)
+	for _, symbol := range sortedSymbols(state) {
+		if symbol == EPS {
+			continue
+		}
+		for _, nextState := range state.Transitions[symbol] {
+			if _, ok := grouped[nextState]; !ok {
+				nextStates = append(nextStates, nextState)
+			}
+			grouped[nextState] = append(grouped[nextState], symbol)
+		}
+	}
+
+	return nextStates, grouped
+}
+
 func (a *NFA) StateByID(stateID int) *State {
 	visited := make(map[int]bool)
 	stack := []*State{a.Start}
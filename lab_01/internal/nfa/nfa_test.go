@@ -1,7 +1,12 @@
 package nfa
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
 )
 
 type testCase struct {
@@ -87,6 +92,80 @@ func TestBuild(t *testing.T) {
 				},
 			},
 		},
+		{
+			input: "[ab]",
+			expected: expectedNFA{
+				startStateID: 0,
+				endStateID:   1,
+				transitions: map[int]transMap{
+					0: {'a': {1}, 'b': {1}},
+				},
+			},
+		},
+		{
+			input: "a[^a-b]|",
+			expected: expectedNFA{
+				startStateID: 4,
+				endStateID:   5,
+				transitions: map[int]transMap{
+					4: {EPS: {0, 2}},
+					0: {'a': {1}},
+					1: {EPS: {5}},
+					2: {charclass.Other: {3}},
+					3: {EPS: {5}},
+				},
+			},
+		},
+		{
+			input: "a{2}",
+			expected: expectedNFA{
+				startStateID: 0,
+				endStateID:   3,
+				transitions: map[int]transMap{
+					0: {'a': {1}},
+					1: {EPS: {2}},
+					2: {'a': {3}},
+				},
+			},
+		},
+		{
+			input: "a{1,2}",
+			expected: expectedNFA{
+				startStateID: 0,
+				endStateID:   5,
+				transitions: map[int]transMap{
+					0: {'a': {1}},
+					1: {EPS: {4}},
+					4: {EPS: {2, 5}},
+					2: {'a': {3}},
+					3: {EPS: {5}},
+				},
+			},
+		},
+		{
+			input: `α\ε|`,
+			expected: expectedNFA{
+				startStateID: 4,
+				endStateID:   5,
+				transitions: map[int]transMap{
+					4: {EPS: {0, 2}},
+					0: {'α': {1}},
+					1: {EPS: {5}},
+					2: {'ε': {3}},
+					3: {EPS: {5}},
+				},
+			},
+		},
+		{
+			input: "a{0}",
+			expected: expectedNFA{
+				startStateID: 2,
+				endStateID:   3,
+				transitions: map[int]transMap{
+					2: {EPS: {3}},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -95,6 +174,105 @@ func TestBuild(t *testing.T) {
 	}
 }
 
+func TestFromAST(t *testing.T) {
+	node, err := ast.Parse("(a|b)c")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	checkNFA(t, FromAST(node), expectedNFA{
+		startStateID: 4,
+		endStateID:   7,
+		transitions: map[int]transMap{
+			4: {EPS: {0, 2}},
+			0: {'a': {1}},
+			1: {EPS: {5}},
+			2: {'b': {3}},
+			3: {EPS: {5}},
+			5: {EPS: {6}},
+			6: {'c': {7}},
+		},
+	})
+}
+
+func TestFromASTWildcard(t *testing.T) {
+	node, err := ast.Parse("a.")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	checkNFA(t, FromAST(node), expectedNFA{
+		startStateID: 0,
+		endStateID:   3,
+		transitions: map[int]transMap{
+			0: {'a': {1}},
+			1: {EPS: {2}},
+			2: {charclass.Other: {3}, 'a': {3}},
+		},
+	})
+}
+
+func TestFromASTEmpty(t *testing.T) {
+	node, err := ast.Parse("(a|)b")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	checkNFA(t, FromAST(node), expectedNFA{
+		startStateID: 4,
+		endStateID:   7,
+		transitions: map[int]transMap{
+			4: {EPS: {0, 2}},
+			0: {'a': {1}},
+			1: {EPS: {5}},
+			2: {EPS: {3}},
+			3: {EPS: {5}},
+			5: {EPS: {6}},
+			6: {'b': {7}},
+		},
+	})
+}
+
+func TestFromASTWith(t *testing.T) {
+	node, err := ast.Parse("x!a")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("FromAST должен паниковать на выражении с !")
+		}
+	}()
+
+	var hookAlphabet charclass.Alphabet
+	result := FromASTWith(node, func(extended *ast.Node, alphabet charclass.Alphabet) *NFA {
+		if extended.Type != ast.NodeNot {
+			t.Errorf("ожидался узел Not, получено %s", extended.Name())
+		}
+		hookAlphabet = alphabet
+
+		start, end := NewState(100), NewState(101)
+		start.Transitions['y'] = []*State{end}
+		return New(start, end)
+	})
+
+	if string(hookAlphabet.Symbols()) != "ax" {
+		t.Errorf("Expected alphabet: ax, Actual: %s", string(hookAlphabet.Symbols()))
+	}
+	checkNFA(t, result, expectedNFA{
+		startStateID: 0,
+		endStateID:   3,
+		transitions: map[int]transMap{
+			0: {'x': {1}},
+			1: {EPS: {2}},
+			2: {'y': {3}},
+		},
+	})
+
+	FromAST(node)
+}
+
 func checkNFA(t *testing.T, nfa *NFA, expected expectedNFA) {
 	if nfa.Start.ID != expected.startStateID {
 		t.Errorf("ожидалось начальное состояние %d, получено - %d", expected.startStateID, nfa.Start.ID)
@@ -150,3 +328,131 @@ func checkTransitions(t *testing.T, state *State, expected map[int]transMap, vis
 		}
 	}
 }
+
+func TestExtractAlphabet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected charclass.Alphabet
+	}{
+		{"ab|", charclass.Alphabet{{Lo: 'a', Hi: 'a'}, {Lo: 'b', Hi: 'b'}}},
+		{"[a-c]", charclass.Alphabet{{Lo: 'a', Hi: 'c'}}},
+		{"[^a-c]", charclass.Alphabet{{Lo: charclass.Other, Hi: charclass.Other}, {Lo: 'a', Hi: 'c'}}},
+		{"x[^\"]*.", charclass.Alphabet{{Lo: charclass.Other, Hi: charclass.Other}, {Lo: '"', Hi: '"'}, {Lo: 'x', Hi: 'x'}}},
+		{"ы[а-в].", charclass.Alphabet{{Lo: 'а', Hi: 'в'}, {Lo: 'ы', Hi: 'ы'}}},
+		{"[a-f][d-z].", charclass.Alphabet{{Lo: 'a', Hi: 'c'}, {Lo: 'd', Hi: 'f'}, {Lo: 'g', Hi: 'z'}}},
+		{`\ε`, charclass.Alphabet{{Lo: 'ε', Hi: 'ε'}}},
+		{"ε", charclass.Alphabet{}},
+	}
+
+	for _, tt := range tests {
+		actual := Build(tt.input).ExtractAlphabet()
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Input: %s, Expected: %q, Actual: %q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestRemoveEpsilon(t *testing.T) {
+	tests := []struct {
+		regex        string
+		startStateID int
+		transitions  map[int]transMap
+		finals       []int
+	}{
+		{
+			regex:        "(a|b)c",
+			startStateID: 4,
+			transitions: map[int]transMap{
+				4: {'a': {1}, 'b': {3}},
+				1: {'c': {7}},
+				3: {'c': {7}},
+			},
+			finals: []int{7},
+		},
+		{
+			regex:        "a*",
+			startStateID: 2,
+			transitions: map[int]transMap{
+				2: {'a': {1}},
+				1: {'a': {1}},
+			},
+			finals: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		node, err := ast.Parse(tt.regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", tt.regex, err)
+		}
+
+		result := FromAST(node).RemoveEpsilon()
+		if result.Start.ID != tt.startStateID {
+			t.Errorf("Regex: %s, ожидалось начальное состояние %d, получено - %d", tt.regex, tt.startStateID, result.Start.ID)
+		}
+		checkTransitions(t, result.Start, tt.transitions, make(map[int]bool))
+
+		finals := []int{}
+		for _, state := range result.States() {
+			if state.IsFinal {
+				finals = append(finals, state.ID)
+			}
+			if len(state.Transitions[EPS]) != 0 {
+				t.Errorf("Regex: %s, у состояния %d остались эпсилон-переходы", tt.regex, state.ID)
+			}
+		}
+		if !reflect.DeepEqual(finals, tt.finals) {
+			t.Errorf("Regex: %s, Expected finals: %v, Actual: %v", tt.regex, tt.finals, finals)
+		}
+		if graph := result.ToGraphviz(); strings.Count(graph, "doublecircle") != len(tt.finals) {
+			t.Errorf("Regex: %s, на графе должно быть %d заключительных:\n%s", tt.regex, len(tt.finals), graph)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	tests := []struct {
+		postfix  string
+		input    string
+		accepted bool
+		frames   int
+		last     string
+	}{
+		{"ab|*a.b.b.", "aabb", true, 6, "Accepted"},
+		{"ab|*a.b.b.", "abab", false, 6, "Rejected"},
+		{"ab|*a.b.b.", "abc", false, 4, "Error: No transition for symbol 'c'"},
+		{"a[^a].", "aя", true, 4, "Accepted"},
+		{"a*", "", true, 2, "Accepted"},
+	}
+
+	for _, tt := range tests {
+		steps, accepted := Build(tt.postfix).Simulate(tt.input)
+		if accepted != tt.accepted {
+			t.Errorf("Postfix: %s, Input: %s, Expected: %v, Actual: %v", tt.postfix, tt.input, tt.accepted, accepted)
+		}
+		if len(steps) != tt.frames {
+			t.Errorf("Postfix: %s, Input: %s, ожидалось %d кадров, получено - %d", tt.postfix, tt.input, tt.frames, len(steps))
+			continue
+		}
+		if !strings.Contains(steps[len(steps)-1], tt.last) {
+			t.Errorf("Postfix: %s, Input: %s, в последнем кадре нет %q:\n%s", tt.postfix, tt.input, tt.last, steps[len(steps)-1])
+		}
+	}
+}
+
+func TestSimulateActiveStates(t *testing.T) {
+	// a|b: 4 -ε-> 0 -a-> 1 -ε-> 5, 4 -ε-> 2 -b-> 3 -ε-> 5
+	steps, _ := Build("ab|").Simulate("a")
+
+	if !strings.Contains(steps[0], `label="Start\n{0, 2, 4}"`) {
+		t.Errorf("в начальном кадре должно быть замыкание {0, 2, 4}:\n%s", steps[0])
+	}
+	for _, expected := range []string{`label="Step 1: Symbol 'a'\n{1, 5}"`, "  1 [color=red, fontcolor=red];", "  5 [color=red, fontcolor=red];"} {
+		if !strings.Contains(steps[1], expected) {
+			t.Errorf("в кадре шага 1 нет %s:\n%s", expected, steps[1])
+		}
+	}
+	if strings.Contains(steps[1], "  0 [color=red") {
+		t.Errorf("состояние 0 не должно быть активным после шага 1:\n%s", steps[1])
+	}
+}
@@ -0,0 +1,131 @@
+package nfa
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+)
+
+// Simulate моделирует работу НКА так же, как DFA.SimulateDFA: первый кадр -
+// эпсилон-замыкание начальных состояний, затем по кадру на каждый символ с
+// множеством активных состояний после перехода и эпсилон-замыкания, последний
+// кадр - итог. Если активных состояний не осталось, последним кадром будет ошибка.
+func (a *NFA) Simulate(input string) ([]string, bool) {
+	alphabet := a.ExtractAlphabet()
+
+	startStates := make(map[int]bool)
+	for _, state := range a.StartStates {
+		startStates[state.ID] = true
+	}
+	active := a.EpsilonClosure(startStates)
+
+	steps := []string{a.ToGraphvizWithHighlight(active, "Start")}
+
+	for i, symbol := range []rune(input) {
+		alphabetSymbol := alphabet.Find(symbol)
+
+		next := make(map[int]bool)
+		for stateID := range active {
+			for _, nextState := range a.StateByID(stateID).Transitions[alphabetSymbol] {
+				next[nextState.ID] = true
+			}
+		}
+
+		if len(next) == 0 {
+			steps = append(steps, a.ToGraphvizWithError(active, symbol))
+			return steps, false
+		}
+
+		active = a.EpsilonClosure(next)
+		steps = append(steps, a.ToGraphvizWithHighlight(active, fmt.Sprintf("Step %d: Symbol '%s'", i+1, charclass.Label([]rune{symbol}, nil))))
+	}
+
+	accepted := a.IsFinalState(active)
+	if accepted {
+		steps = append(steps, a.ToGraphvizWithHighlight(active, "Accepted"))
+	} else {
+		steps = append(steps, a.ToGraphvizWithHighlight(active, "Rejected"))
+	}
+
+	return steps, accepted
+}
+
+// ToGraphvizWithHighlight рисует автомат, выделяя все активные состояния, и
+// подписывает кадр множеством активных состояний.
+func (a *NFA) ToGraphvizWithHighlight(active map[int]bool, description string) string {
+	graph := "digraph NFA {\n"
+	graph += "  rankdir=LR;\n"
+	graph += "  node [shape = circle];\n"
+
+	graph += "  start [shape = point];\n"
+	for _, state := range a.startStates() {
+		graph += fmt.Sprintf("  start -> %d;\n", state.ID)
+	}
+
+	graph += a.statesToGraphviz()
+	graph += highlight(active)
+
+	graph += "  labelloc=\"t\";\n"
+	graph += fmt.Sprintf("  label=\"%s\\n%s\";\n", description, formatStates(active))
+
+	graph += "}\n"
+	return graph
+}
+
+// ToGraphvizWithError рисует автомат с активными состояниями, из которых нет
+// перехода по symbol.
+func (a *NFA) ToGraphvizWithError(active map[int]bool, symbol rune) string {
+	graph := "digraph NFA {\n"
+	graph += "  rankdir=LR;\n"
+	graph += "  node [shape = circle];\n"
+
+	graph += "  start [shape = point];\n"
+	for _, state := range a.startStates() {
+		graph += fmt.Sprintf("  start -> %d;\n", state.ID)
+	}
+
+	graph += a.statesToGraphviz()
+	graph += highlight(active)
+
+	label := charclass.Label([]rune{symbol}, nil)
+	for _, stateID := range sortedStateIDs(active) {
+		graph += fmt.Sprintf("  %d -> error [label=\"%s\", color=red, fontcolor=red];\n", stateID, label)
+	}
+	graph += "  error [shape=box, color=red, fontcolor=red];\n"
+
+	graph += "  labelloc=\"t\";\n"
+	graph += fmt.Sprintf("  label=\"Error: No transition for symbol '%s'\";\n", label)
+
+	graph += "}\n"
+	return graph
+}
+
+func highlight(active map[int]bool) string {
+	graph := ""
+	for _, stateID := range sortedStateIDs(active) {
+		graph += fmt.Sprintf("  %d [color=red, fontcolor=red];\n", stateID)
+	}
+	return graph
+}
+
+func sortedStateIDs(states map[int]bool) []int {
+	ids := make([]int, 0, len(states))
+	for stateID := range states {
+		ids = append(ids, stateID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// formatStates записывает множество состояний как {1, 3, 5}.
+func formatStates(states map[int]bool) string {
+	label := "{"
+	for i, stateID := range sortedStateIDs(states) {
+		if i > 0 {
+			label += ", "
+		}
+		label += fmt.Sprint(stateID)
+	}
+	return label + "}"
+}
@@ -0,0 +1,277 @@
+package pikevm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/charclass"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/lexer"
+)
+
+// Mode - правило выбора совпадения и подгрупп.
+type Mode int
+
+const (
+	// LeftmostFirst - как в Perl: самое левое совпадение, среди них - первое по
+	// приоритету ветвей (левая альтернатива, жадные квантификаторы).
+	LeftmostFirst Mode = iota
+	// LeftmostLongest - как в POSIX: самое левое совпадение, среди них - самое
+	// длинное; подгруппы сравниваются по порядку: раньше начало, затем дальше конец.
+	LeftmostLongest
+)
+
+const (
+	opSymbol = iota // один символ из класса
+	opSplit         // продолжить с X и с Y, X в приоритете
+	opJump
+	opSave // записать позицию в слот Slot (2k - начало группы k, 2k+1 - конец)
+	opBegin
+	opEnd
+	opMatch
+)
+
+var opNames = map[int]string{
+	opSymbol: "symbol",
+	opSplit:  "split",
+	opJump:   "jmp",
+	opSave:   "save",
+	opBegin:  "assert ^",
+	opEnd:    "assert $",
+	opMatch:  "match",
+}
+
+type instruction struct {
+	op    int
+	class charclass.Class
+	x, y  int
+	slot  int
+}
+
+// Program - выражение, скомпилированное в команды Pike VM. Группа 0 - все
+// совпадение, группы 1..Groups() - захватывающие скобки по порядку открытия.
+type Program struct {
+	instructions []instruction
+	groups       int
+}
+
+// Group - позиции группы в рунах, символы с Start по End-1. Если группа не
+// участвовала в совпадении, Start и End равны -1.
+type Group struct {
+	Start int
+	End   int
+}
+
+// ErrExtended - у пересечения и дополнения нет конструкции Томпсона, а значит
+// и программы для Pike VM.
+var ErrExtended = errors.New("пересечение и дополнение не поддерживаются в Pike VM")
+
+// Compile переводит дерево выражения (ast.Parse) в программу: каждая группа
+// (...) обрамляется командами save, повторы {n,m} раскрываются в копии, которые
+// пишут в одни и те же слоты, поэтому группа хранит последнюю итерацию.
+func Compile(node *ast.Node) (*Program, error) {
+	if ast.IsExtended(node) {
+		return nil, ErrExtended
+	}
+
+	c := &compiler{groups: countGroups(node)}
+	c.emit(instruction{op: opSave, slot: 0})
+	c.compile(node)
+	c.emit(instruction{op: opSave, slot: 1})
+	c.emit(instruction{op: opMatch})
+
+	return &Program{instructions: c.instructions, groups: c.groups}, nil
+}
+
+// Groups - число захватывающих групп без группы 0.
+func (p *Program) Groups() int {
+	return p.groups
+}
+
+// String выводит программу по одной команде в строке.
+func (p *Program) String() string {
+	var builder strings.Builder
+	for pc, inst := range p.instructions {
+		fmt.Fprintf(&builder, "%3d  %s", pc, opNames[inst.op])
+		switch inst.op {
+		case opSymbol:
+			fmt.Fprintf(&builder, " %s", inst.class)
+		case opSplit:
+			fmt.Fprintf(&builder, " %d, %d", inst.x, inst.y)
+		case opJump:
+			fmt.Fprintf(&builder, " %d", inst.x)
+		case opSave:
+			fmt.Fprintf(&builder, " %d", inst.slot)
+		}
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+type compiler struct {
+	instructions []instruction
+	groups       int
+}
+
+func (c *compiler) emit(inst instruction) int {
+	c.instructions = append(c.instructions, inst)
+	return len(c.instructions) - 1
+}
+
+func (c *compiler) next() int {
+	return len(c.instructions)
+}
+
+func (c *compiler) compile(node *ast.Node) {
+	switch node.Type {
+	case ast.NodeLiteral, ast.NodeClass:
+		c.emit(instruction{op: opSymbol, class: node.EffectiveClass()})
+	case ast.NodeAny:
+		c.emit(instruction{op: opSymbol, class: charclass.Class{Negated: true}})
+	case ast.NodeBegin:
+		c.emit(instruction{op: opBegin})
+	case ast.NodeEnd:
+		c.emit(instruction{op: opEnd})
+	case ast.NodeEmpty, ast.NodeFlags:
+	case ast.NodeConcat:
+		for _, child := range node.Children {
+			c.compile(child)
+		}
+	case ast.NodeAlt:
+		// split L1, next; L1: ветка 1; jmp end; ... ; последняя ветка; end:
+		jumps := []int{}
+		for i, child := range node.Children {
+			if i == len(node.Children)-1 {
+				c.compile(child)
+				break
+			}
+			split := c.emit(instruction{op: opSplit})
+			c.instructions[split].x = c.next()
+			c.compile(child)
+			jumps = append(jumps, c.emit(instruction{op: opJump}))
+			c.instructions[split].y = c.next()
+		}
+		for _, jump := range jumps {
+			c.instructions[jump].x = c.next()
+		}
+	case ast.NodeStar:
+		c.star(node.Children[0])
+	case ast.NodePlus:
+		c.plus(node.Children[0])
+	case ast.NodeOptional:
+		c.optional(node.Children[0])
+	case ast.NodeRepeat:
+		c.repeat(node.Children[0], node.Min, node.Max)
+	case ast.NodeGroup:
+		if node.Capture == 0 {
+			c.compile(node.Children[0])
+			break
+		}
+		c.emit(instruction{op: opSave, slot: 2 * node.Capture})
+		c.compile(node.Children[0])
+		c.emit(instruction{op: opSave, slot: 2*node.Capture + 1})
+	}
+}
+
+// countGroups - наибольший номер группы: группа внутри {0} не попадает в
+// программу, но номер за ней сохраняется.
+func countGroups(node *ast.Node) int {
+	count := node.Capture
+	for _, child := range node.Children {
+		count = max(count, countGroups(child))
+	}
+	return count
+}
+
+// star: L: split body, end; body; jmp L; end:
+//
+// Если тело допускает пустую строку, звезда компилируется как (body+)?, как в
+// RE2: иначе пустая итерация приходит в уже посещенный split и отбрасывается, и
+// выход из цикла достается потоку с меньшим приоритетом.
+func (c *compiler) star(child *ast.Node) {
+	if nullable(child) {
+		split := c.emit(instruction{op: opSplit})
+		c.instructions[split].x = c.next()
+		c.plus(child)
+		c.instructions[split].y = c.next()
+		return
+	}
+
+	split := c.emit(instruction{op: opSplit})
+	c.instructions[split].x = c.next()
+	c.compile(child)
+	c.emit(instruction{op: opJump, x: split})
+	c.instructions[split].y = c.next()
+}
+
+// nullable - допускает ли выражение пустую строку.
+func nullable(node *ast.Node) bool {
+	switch node.Type {
+	case ast.NodeLiteral, ast.NodeClass, ast.NodeAny:
+		return false
+	case ast.NodeConcat:
+		for _, child := range node.Children {
+			if !nullable(child) {
+				return false
+			}
+		}
+		return true
+	case ast.NodeAlt:
+		for _, child := range node.Children {
+			if nullable(child) {
+				return true
+			}
+		}
+		return false
+	case ast.NodeStar, ast.NodeOptional:
+		return true
+	case ast.NodeRepeat:
+		return node.Min == 0 || nullable(node.Children[0])
+	case ast.NodePlus, ast.NodeGroup:
+		return nullable(node.Children[0])
+	}
+	// ε, якоря и флаги не читают символов
+	return true
+}
+
+// plus: L: body; split L, end; end:
+func (c *compiler) plus(child *ast.Node) {
+	start := c.next()
+	c.compile(child)
+	split := c.emit(instruction{op: opSplit, x: start})
+	c.instructions[split].y = c.next()
+}
+
+// optional: split body, end; body; end:
+func (c *compiler) optional(child *ast.Node) {
+	split := c.emit(instruction{op: opSplit})
+	c.instructions[split].x = c.next()
+	c.compile(child)
+	c.instructions[split].y = c.next()
+}
+
+// repeat раскрывает {min,max}: min копий, затем max-min необязательных или
+// звезда при max = lexer.Unbounded. {min,} при min > 0 раскрывается как min-1
+// копий и плюс, а не min копий и звезда: иначе тело, допускающее пустую
+// строку, получает лишнюю пустую итерацию и группа расходится с x+.
+func (c *compiler) repeat(child *ast.Node, min, max int) {
+	if max == lexer.Unbounded && min > 0 {
+		for i := 0; i < min-1; i++ {
+			c.compile(child)
+		}
+		c.plus(child)
+		return
+	}
+
+	for i := 0; i < min; i++ {
+		c.compile(child)
+	}
+	if max == lexer.Unbounded {
+		c.star(child)
+		return
+	}
+	for i := min; i < max; i++ {
+		c.optional(child)
+	}
+}
@@ -0,0 +1,115 @@
+package pikevm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/dfa"
+)
+
+func compile(t *testing.T, regex string) *Program {
+	node, err := ast.Parse(regex)
+	if err != nil {
+		t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+	}
+	program, err := Compile(node)
+	if err != nil {
+		t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+	}
+	return program
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		regex    string
+		input    string
+		mode     Mode
+		expected []Group
+	}{
+		{"(a+)(b+)", "xaabbby", LeftmostFirst, []Group{{1, 6}, {1, 3}, {3, 6}}},
+		{"(a|ab)(c|bcd)(d*)", "abcd", LeftmostFirst, []Group{{0, 4}, {0, 1}, {1, 4}, {4, 4}}},
+		{"(a|ab)(c|bcd)(d*)", "abcd", LeftmostLongest, []Group{{0, 4}, {0, 2}, {2, 3}, {3, 4}}},
+		{"a|ab", "ab", LeftmostFirst, []Group{{0, 1}}},
+		{"a|ab", "ab", LeftmostLongest, []Group{{0, 2}}},
+		// звезда с допускающим пустую строку телом делает одну пустую итерацию, как в RE2
+		{"(a*)*", "b", LeftmostFirst, []Group{{0, 0}, {0, 0}}},
+		{"(a*)*", "", LeftmostFirst, []Group{{0, 0}, {0, 0}}},
+		{"(a*)*", "aa", LeftmostFirst, []Group{{0, 2}, {0, 2}}},
+		{"(|a)*", "a", LeftmostFirst, []Group{{0, 0}, {0, 0}}},
+		{"(a*|b)*", "b", LeftmostFirst, []Group{{0, 0}, {0, 0}}},
+		{"(|a)+", "a", LeftmostFirst, []Group{{0, 0}, {0, 0}}},
+		{"(a|)*", "aa", LeftmostFirst, []Group{{0, 2}, {1, 2}}},
+		{"(b?){1,}", "b", LeftmostFirst, []Group{{0, 1}, {0, 1}}},
+		{"(b?)+", "b", LeftmostFirst, []Group{{0, 1}, {0, 1}}},
+		{"(b?){2,}", "bb", LeftmostFirst, []Group{{0, 2}, {1, 2}}},
+		{"(ab){2,}", "ababab", LeftmostFirst, []Group{{0, 6}, {4, 6}}},
+		{"(a)|(b)", "b", LeftmostFirst, []Group{{0, 1}, {-1, -1}, {0, 1}}},
+		{"(ab){2}", "ababab", LeftmostFirst, []Group{{0, 4}, {2, 4}}},
+		{"(?:a)(b)(?i:(c))", "abC", LeftmostFirst, []Group{{0, 3}, {1, 2}, {2, 3}}},
+		{"(x)?y", "y", LeftmostLongest, []Group{{0, 1}, {-1, -1}}},
+		{"^(a)", "ba", LeftmostFirst, nil},
+		{"(b)$", "bab", LeftmostFirst, []Group{{2, 3}, {2, 3}}},
+		{"(.)[^a]", "яaбв", LeftmostFirst, []Group{{1, 3}, {1, 2}}},
+		{"(a){0}b", "ab", LeftmostFirst, []Group{{1, 2}, {-1, -1}}},
+	}
+
+	for _, tt := range tests {
+		groups, ok := compile(t, tt.regex).Find(tt.input, tt.mode)
+		if ok != (tt.expected != nil) || !reflect.DeepEqual(groups, tt.expected) {
+			t.Errorf("Regex: %s, Input: %s, Mode: %d, Expected: %v, Actual: %v", tt.regex, tt.input, tt.mode, tt.expected, groups)
+		}
+	}
+}
+
+func TestFindMatchesSearch(t *testing.T) {
+	tests := []string{"(a|b)*abb", "a[^b]*b", "(ab)+|c", "x?y*", "[0-9]{2,3}"}
+	inputs := []string{"", "abb", "babba", "aab", "cabab", "xyyy", "12345", "a1b22", "zz"}
+
+	for _, regex := range tests {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+		program, err := Compile(node)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+		compiled := dfa.Compile(node)
+
+		for _, input := range inputs {
+			expected, found := compiled.Search(input)
+			groups, ok := program.Find(input, LeftmostLongest)
+			if ok != found || (ok && (groups[0].Start != expected.Start || groups[0].End != expected.End)) {
+				t.Errorf("Regex: %s, Input: %q, Expected: %v %v, Actual: %v %v", regex, input, found, expected, ok, groups)
+			}
+		}
+	}
+}
+
+func TestCompileExtended(t *testing.T) {
+	node, err := ast.Parse("a&!b")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, err := Compile(node); !errors.Is(err, ErrExtended) {
+		t.Errorf("ожидалась ошибка ErrExtended, получено %v", err)
+	}
+}
+
+func TestProgramString(t *testing.T) {
+	expected := "" +
+		"  0  save 0\n" +
+		"  1  save 2\n" +
+		"  2  split 3, 5\n" +
+		"  3  symbol [a]\n" +
+		"  4  jmp 2\n" +
+		"  5  save 3\n" +
+		"  6  symbol [b]\n" +
+		"  7  save 1\n" +
+		"  8  match\n"
+	if actual := compile(t, "(a*)b").String(); actual != expected {
+		t.Errorf("Expected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,158 @@
+package pikevm
+
+// thread - поток Pike VM: команда и слоты групп, -1 - позиция не записана.
+type thread struct {
+	pc    int
+	slots []int
+}
+
+// threadList - потоки одного шага в порядке приоритета, не больше одного на команду.
+type threadList struct {
+	threads []thread
+	index   []int // номер потока в threads для команды, -1 - потока нет
+}
+
+func newThreadList(size int) *threadList {
+	list := &threadList{index: make([]int, size)}
+	list.clear()
+	return list
+}
+
+func (list *threadList) clear() {
+	list.threads = list.threads[:0]
+	for i := range list.index {
+		list.index[i] = -1
+	}
+}
+
+// machine - состояние одного поиска.
+type machine struct {
+	program *Program
+	mode    Mode
+	input   []rune
+}
+
+// Find ищет первое совпадение в input по правилу mode и возвращает позиции всех
+// групп в рунах: группа 0 - все совпадение, дальше группы по номерам.
+func (p *Program) Find(input string, mode Mode) ([]Group, bool) {
+	m := &machine{program: p, mode: mode, input: []rune(input)}
+
+	size := len(p.instructions)
+	current, next := newThreadList(size), newThreadList(size)
+	var matched []int
+
+	for pos := 0; ; pos++ {
+		// новый поток с текущей позиции - самый низкий приоритет; после первого
+		// совпадения более правые начала не нужны
+		if matched == nil {
+			m.add(current, 0, m.emptySlots(), pos)
+		}
+		if len(current.threads) == 0 {
+			break
+		}
+
+		next.clear()
+		for _, t := range current.threads {
+			inst := p.instructions[t.pc]
+			switch inst.op {
+			case opSymbol:
+				if pos < len(m.input) && inst.class.Contains(m.input[pos]) {
+					m.add(next, t.pc+1, t.slots, pos+1)
+				}
+			case opMatch:
+				// в leftmost-first все оставшиеся потоки выше по приоритету, чем
+				// прежнее совпадение, поэтому более позднее совпадение его заменяет
+				if matched == nil || mode == LeftmostFirst || better(t.slots, matched) {
+					matched = t.slots
+				}
+			}
+			// потоки ниже совпавшего по приоритету в leftmost-first не нужны
+			if mode == LeftmostFirst && inst.op == opMatch {
+				break
+			}
+		}
+
+		if pos == len(m.input) {
+			break
+		}
+		current, next = next, current
+	}
+
+	if matched == nil {
+		return nil, false
+	}
+
+	groups := make([]Group, p.groups+1)
+	for i := range groups {
+		groups[i] = Group{Start: matched[2*i], End: matched[2*i+1]}
+		if groups[i].Start < 0 || groups[i].End < 0 {
+			groups[i] = Group{Start: -1, End: -1}
+		}
+	}
+	return groups, true
+}
+
+func (m *machine) emptySlots() []int {
+	slots := make([]int, 2*(m.program.groups+1))
+	for i := range slots {
+		slots[i] = -1
+	}
+	return slots
+}
+
+// add добавляет поток и проходит от него по командам, не читающим символ. Если
+// поток на этой команде уже есть, в leftmost-first новый отбрасывается (у него
+// ниже приоритет), а в leftmost-longest заменяет старый, если его группы лучше.
+func (m *machine) add(list *threadList, pc int, slots []int, pos int) {
+	if i := list.index[pc]; i >= 0 {
+		if m.mode == LeftmostFirst || !better(slots, list.threads[i].slots) {
+			return
+		}
+		list.threads[i].slots = slots
+	} else {
+		list.index[pc] = len(list.threads)
+		list.threads = append(list.threads, thread{pc: pc, slots: slots})
+	}
+
+	inst := m.program.instructions[pc]
+	switch inst.op {
+	case opJump:
+		m.add(list, inst.x, slots, pos)
+	case opSplit:
+		m.add(list, inst.x, slots, pos)
+		m.add(list, inst.y, slots, pos)
+	case opSave:
+		updated := append([]int{}, slots...)
+		updated[inst.slot] = pos
+		m.add(list, pc+1, updated, pos)
+	case opBegin:
+		if pos == 0 {
+			m.add(list, pc+1, slots, pos)
+		}
+	case opEnd:
+		if pos == len(m.input) {
+			m.add(list, pc+1, slots, pos)
+		}
+	}
+}
+
+// better сравнивает слоты по правилу POSIX: группы по порядку начиная с 0,
+// у каждой лучше более раннее начало (записанное лучше незаписанного), затем
+// более поздний конец.
+func better(a, b []int) bool {
+	for i := 0; i < len(a); i += 2 {
+		if a[i] != b[i] {
+			switch {
+			case a[i] < 0:
+				return false
+			case b[i] < 0:
+				return true
+			}
+			return a[i] < b[i]
+		}
+		if a[i+1] != b[i+1] {
+			return a[i+1] > b[i+1]
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+// Package regextest - общие выражения и проверка для тестов, которые сверяют
+// разные способы построения ДКА с dfa.Compile.
+package regextest
+
+import (
+	"testing"
+
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/ast"
+	"github.com/Erlendum/BMSTU_CC/lab_01/internal/dfa"
+)
+
+// Regexes - выражения без пересечения и дополнения: группы, повторы, пустая
+// строка, классы, флаги, якоря и не-ASCII символы.
+var Regexes = []string{
+	"(ab)*c",
+	"(a|b)*abb",
+	"(a(b|c)*d)*(ad)*c",
+	"a*b*|b*a*",
+	"[0-9]{2,4}",
+	"(ab){0,}",
+	"a{3,}b?",
+	"(a|ε)(b|ε)",
+	"ε",
+	"",
+	"(?i)ab",
+	"a[^b]*b",
+	".*x.",
+	"^a+$",
+	"привет|мир",
+	"[a-f]+[d-z]*|[一-龥]",
+}
+
+// Extended - выражения с пересечением и дополнением для способов, которые их строят.
+var Extended = []string{
+	"[a-z]+&!(if|else)",
+	"(a|b)*&!(.*bb.*)",
+	"!(a*)",
+	"!.*",
+	"a&b",
+	"(.*a.*)&(.*b.*)&!(.*c.*)",
+}
+
+// MatchesCompile строит ДКА для каждого выражения функцией build и проверяет,
+// что после минимизации он эквивалентен dfa.Compile и у него столько же
+// состояний. Проверки, особые для способа построения, делает сама build.
+func MatchesCompile(t *testing.T, regexes []string, build func(t *testing.T, regex string, node *ast.Node) *dfa.DFA) {
+	t.Helper()
+
+	for _, regex := range regexes {
+		node, err := ast.Parse(regex)
+		if err != nil {
+			t.Fatalf("Regex: %s, неожиданная ошибка: %v", regex, err)
+		}
+
+		expected := dfa.Compile(node)
+		built := build(t, regex, node)
+		if built == nil {
+			continue
+		}
+		minimized := built.Minimize()
+
+		if equivalent, counterexample := dfa.Equivalent(minimized, expected); !equivalent {
+			t.Errorf("Regex: %s, автоматы расходятся на %q", regex, counterexample)
+		}
+		if len(minimized.States) != len(expected.States) {
+			t.Errorf("Regex: %s, состояний %d вместо %d", regex, len(minimized.States), len(expected.States))
+		}
+	}
+}